@@ -0,0 +1,68 @@
+// Command breachfilter turns a dump of breached password SHA-1 hashes, one
+// "HASH" or "HASH:count" per line as published by Have I Been Pwned, into the
+// filter Chirpy loads from BREACHED_PASSWORDS_FILE.
+//
+//	breachfilter -in pwned-passwords-sha1.txt -out breached.bloom
+package main
+
+import (
+	"bufio"
+	"flag"
+	"log"
+	"os"
+
+	"github.com/anton-jj/chripy/internal/password"
+)
+
+func main() {
+	in := flag.String("in", "", "file with one SHA-1 hash per line")
+	out := flag.String("out", "breached.bloom", "where to write the filter")
+	fpRate := flag.Float64("fp", 0.001, "false positive rate")
+	flag.Parse()
+	if *in == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	// Count first so the filter can be sized before anything is added.
+	n, err := forEachHash(*in, func([20]byte) {})
+	if err != nil {
+		log.Fatal(err)
+	}
+	filter := password.NewBloomFilter(n, *fpRate)
+	if _, err := forEachHash(*in, filter.AddSHA1); err != nil {
+		log.Fatal(err)
+	}
+
+	file, err := os.Create(*out)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if _, err := filter.WriteTo(file); err != nil {
+		log.Fatal(err)
+	}
+	if err := file.Close(); err != nil {
+		log.Fatal(err)
+	}
+	log.Printf("wrote %d hashes to %s", n, *out)
+}
+
+func forEachHash(path string, fn func([20]byte)) (int, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	n := 0
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		sum, ok := password.ParseSHA1Line(scanner.Text())
+		if !ok {
+			continue
+		}
+		fn(sum)
+		n++
+	}
+	return n, scanner.Err()
+}
@@ -0,0 +1,47 @@
+// Command setrole changes a user's role. It is how the first admin is made:
+//
+//	setrole -email you@example.com -role admin
+//
+// The database is taken from DB_URL, read from .env like the server does.
+package main
+
+import (
+	"context"
+	"database/sql"
+	"flag"
+	"log"
+	"os"
+
+	"github.com/anton-jj/chripy/internal/database"
+	"github.com/joho/godotenv"
+	_ "github.com/lib/pq"
+)
+
+func main() {
+	email := flag.String("email", "", "email of the user to change")
+	role := flag.String("role", "", "user, moderator or admin")
+	flag.Parse()
+	if *email == "" || *role == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	godotenv.Load()
+	db, err := sql.Open("postgres", os.Getenv("DB_URL"))
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer db.Close()
+
+	n, err := database.New(db).SetUserRole(context.Background(), database.SetUserRoleParams{
+		Role:  *role,
+		Email: *email,
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+	if n == 0 {
+		log.Fatalf("no user with email %s", *email)
+	}
+	log.Printf("%s is now %s", *email, *role)
+}
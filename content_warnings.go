@@ -0,0 +1,137 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/anton-jj/chripy/internal/audit"
+	"github.com/anton-jj/chripy/internal/database"
+	"github.com/google/uuid"
+)
+
+// How chirps marked sensitive are returned in list responses. Signed out
+// readers get sensitiveCollapse.
+const (
+	sensitiveCollapse = "collapse"
+	sensitiveExpand   = "expand"
+	sensitiveHide     = "hide"
+)
+
+const maxContentWarningLength = 100
+
+func validSensitivePreference(pref string) bool {
+	switch pref {
+	case sensitiveCollapse, sensitiveExpand, sensitiveHide:
+		return true
+	}
+	return false
+}
+
+// normalizeContentWarning trims the warning and checks its length. A chirp
+// with a content warning is always sensitive.
+func normalizeContentWarning(warning string, sensitive bool) (string, bool, bool) {
+	warning = strings.TrimSpace(warning)
+	if utf8.RuneCountInString(warning) > maxContentWarningLength {
+		return "", false, false
+	}
+	return warning, sensitive || warning != "", true
+}
+
+// sensitivePreference is the signed in reader's sensitive_content setting.
+func (aCfg *apiConfig) sensitivePreference(r *http.Request) string {
+	viewer, ok := userIDFromContext(r.Context())
+	if !ok {
+		return sensitiveCollapse
+	}
+	user, err := aCfg.db.GetUserById(r.Context(), viewer)
+	if err != nil {
+		return sensitiveCollapse
+	}
+	return user.SensitiveContent
+}
+
+// applySensitivePreference shapes a chirp list for the reader. Collapsed
+// chirps keep their warning but drop the body, which clients fetch from
+// GET /api/chirps/{chirpID} when the reader opens them. The reader's own
+// chirps are always returned in full.
+func (aCfg *apiConfig) applySensitivePreference(r *http.Request, chirps []Chirp) []Chirp {
+	pref := aCfg.sensitivePreference(r)
+	if pref == sensitiveExpand {
+		return chirps
+	}
+	viewer, _ := userIDFromContext(r.Context())
+	shaped := make([]Chirp, 0, len(chirps))
+	for _, c := range chirps {
+		if c.Sensitive && c.User_id != viewer {
+			if pref == sensitiveHide {
+				continue
+			}
+			c.Body = ""
+			c.Links = nil
+			c.Preview = nil
+			c.Collapsed = true
+		}
+		shaped = append(shaped, c)
+	}
+	return shaped
+}
+
+// handleAdminSetChirpSensitivity lets moderators set or clear a chirp's
+// content warning and sensitive flag, whatever its author chose.
+func (aCfg *apiConfig) handleAdminSetChirpSensitivity(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	type parameters struct {
+		ContentWarning *string `json:"content_warning"`
+		Sensitive      *bool   `json:"sensitive"`
+	}
+
+	id, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		respondWithError(w, 400, "invalid chirp id")
+		return
+	}
+	var params parameters
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+		respondWithError(w, 400, "invalid json format")
+		return
+	}
+
+	chirp, err := aCfg.db.GetChirpById(r.Context(), id)
+	if err != nil {
+		respondWithError(w, 404, "chirp not found")
+		return
+	}
+	warning, sensitive := chirp.ContentWarning, chirp.Sensitive
+	if params.ContentWarning != nil {
+		warning = *params.ContentWarning
+	}
+	if params.Sensitive != nil {
+		sensitive = *params.Sensitive
+	}
+	warning, sensitive, ok := normalizeContentWarning(warning, sensitive)
+	if !ok {
+		respondWithError(w, 400, "content warning is too long")
+		return
+	}
+
+	updated, err := aCfg.db.SetChirpSensitivity(r.Context(), database.SetChirpSensitivityParams{
+		ContentWarning: warning,
+		Sensitive:      sensitive,
+		ID:             chirp.ID,
+	})
+	if err != nil {
+		respondWithError(w, 500, "Failed to update database")
+		return
+	}
+	aCfg.recordAdminAudit(r, audit.ActionAdminChirpMarked, chirp.UserID, map[string]interface{}{
+		"chirp_id":        chirp.ID,
+		"content_warning": warning,
+		"sensitive":       sensitive,
+	})
+	resp := []Chirp{toChirp(updated)}
+	aCfg.decorateChirps(r, resp)
+	respondWithJson(w, 200, resp[0])
+}
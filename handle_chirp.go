@@ -1,42 +1,64 @@
 package main
 
 import (
+	"database/sql"
 	"encoding/json"
 	"log"
 	"net/http"
 	"strings"
 	"time"
 
-	"github.com/anton-jj/chripy/internal/auth"
+	"github.com/anton-jj/chripy/internal/audit"
 	"github.com/anton-jj/chripy/internal/database"
+	"github.com/anton-jj/chripy/internal/linkpreview"
+	"github.com/anton-jj/chripy/internal/moderation"
+	"github.com/anton-jj/chripy/internal/spam"
+	"github.com/anton-jj/chripy/internal/textlen"
 
 	"github.com/google/uuid"
 )
 
+// chirpMaxBytes caps the size of a chirp body before it is measured. It is
+// well above what any chirp within the length limit needs, links included.
+const chirpMaxBytes = 10 << 10
+
 type Chirp struct {
-	Id        uuid.UUID `json:"id"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
-	Body      string    `json:"body"`
-	User_id   uuid.UUID `json:"user_id"`
+	Id             uuid.UUID            `json:"id"`
+	CreatedAt      time.Time            `json:"created_at"`
+	UpdatedAt      time.Time            `json:"updated_at"`
+	Body           string               `json:"body"`
+	User_id        uuid.UUID            `json:"user_id"`
+	ContentWarning string               `json:"content_warning"`
+	Sensitive      bool                 `json:"sensitive"`
+	Collapsed      bool                 `json:"collapsed,omitempty"`
+	Links          []chirpLink          `json:"links,omitempty"`
+	Preview        *linkpreview.Preview `json:"preview,omitempty"`
+	Reactions      []reactionCount      `json:"reactions"`
+	MyReactions    []string             `json:"my_reactions,omitempty"`
 }
 
-func (aCfg *apiConfig) handleDeleteChirp(w http.ResponseWriter, r *http.Request) {
-	tok, err := auth.GetBearerToken(r.Header)
-	if err != nil {
-		respondWithError(w, 401, "header missing or malformed")
-		return
-	}
-	if strings.Count(tok, ".") != 2 {
-		respondWithError(w, 401, "header malformed")
-		return
+func toChirp(chirp database.Chirp) Chirp {
+	return Chirp{
+		Id:             chirp.ID,
+		CreatedAt:      chirp.CreatedAt,
+		UpdatedAt:      chirp.UpdatedAt,
+		Body:           chirp.Body,
+		User_id:        chirp.UserID,
+		ContentWarning: chirp.ContentWarning,
+		Sensitive:      chirp.Sensitive,
+		Reactions:      []reactionCount{},
 	}
+}
 
-	userId, err := auth.ValidateJWT(tok, aCfg.secret)
-	if err != nil {
-		respondWithError(w, 403, "Unauthorized")
-		return
-	}
+// decorateChirps adds what a chirp response carries besides the chirp row:
+// link entities and previews, and reactions.
+func (aCfg *apiConfig) decorateChirps(r *http.Request, chirps []Chirp) {
+	aCfg.attachLinks(r, chirps)
+	aCfg.attachReactions(r, chirps)
+}
+
+func (aCfg *apiConfig) handleDeleteChirp(w http.ResponseWriter, r *http.Request) {
+	userId, _ := userIDFromContext(r.Context())
 
 	id, err := uuid.Parse(r.PathValue("chirpID"))
 	if err != nil {
@@ -59,6 +81,7 @@ func (aCfg *apiConfig) handleDeleteChirp(w http.ResponseWriter, r *http.Request)
 		respondWithError(w, 404, "Chirp is not found")
 		return
 	}
+	aCfg.recordAudit(r, audit.ActionChirpDeleted, userId, chirp.UserID, map[string]interface{}{"chirp_id": chirp.ID})
 	respondWithJson(w, 204, nil)
 
 }
@@ -73,40 +96,44 @@ func (aCfg *apiConfig) handleGetChirp(w http.ResponseWriter, r *http.Request) {
 		respondWithError(w, 404, "chirp not found")
 		return
 	}
-
-	resp := Chirp{
-		Id:        chirp.ID,
-		CreatedAt: chirp.CreatedAt,
-		UpdatedAt: chirp.UpdatedAt,
-		Body:      chirp.Body,
-		User_id:   chirp.UserID,
+	if !aCfg.canViewChirp(r, chirp) {
+		respondWithError(w, 404, "chirp not found")
+		return
 	}
 
-	respondWithJson(w, 200, resp)
+	resp := []Chirp{toChirp(chirp)}
+	aCfg.decorateChirps(r, resp)
+	respondWithJson(w, 200, resp[0])
 }
 func (aCfg *apiConfig) handleChirpsGetAll(w http.ResponseWriter, r *http.Request) {
-	chirps, err := aCfg.db.GetAllChirps(r.Context())
+	var chirps []database.Chirp
+	var err error
+	if viewer, ok := userIDFromContext(r.Context()); ok {
+		chirps, err = aCfg.db.GetChirpsForViewer(r.Context(), viewer)
+	} else {
+		chirps, err = aCfg.db.GetAllChirps(r.Context())
+	}
 	if err != nil {
 		respondWithError(w, 500, "failed to get chirps")
+		return
 	}
 
+	chirps = aCfg.applyKeywordFilters(r, filterContextPublic, chirps)
+
 	var jsonChirps []Chirp
 	for _, chirp := range chirps {
-		jsonChirps = append(jsonChirps, Chirp{
-			Id:        chirp.ID,
-			CreatedAt: chirp.CreatedAt,
-			UpdatedAt: chirp.UpdatedAt,
-			Body:      chirp.Body,
-			User_id:   chirp.UserID,
-		})
+		jsonChirps = append(jsonChirps, toChirp(chirp))
 	}
-	respondWithJson(w, 200, jsonChirps)
+	aCfg.decorateChirps(r, jsonChirps)
+	respondWithJson(w, 200, aCfg.applySensitivePreference(r, jsonChirps))
 
 }
 func (aCfg *apiConfig) handleChirpCreate(w http.ResponseWriter, r *http.Request) {
 
 	type parameters struct {
-		Body string `json:"body"`
+		Body           string `json:"body"`
+		ContentWarning string `json:"content_warning"`
+		Sensitive      bool   `json:"sensitive"`
 	}
 
 	var params parameters
@@ -116,35 +143,82 @@ func (aCfg *apiConfig) handleChirpCreate(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	if len(params.Body) > 140 {
-		respondWithError(w, 400, "chirp to long")
+	// The byte cap comes first so normalizing and measuring never work on an
+	// arbitrarily large body.
+	if len(params.Body) > chirpMaxBytes {
+		respondWithError(w, 400, "chirp too long")
 		return
 	}
 
-	var cleanedBody string = validateBody(params.Body)
-
-	token, err := auth.GetBearerToken(r.Header)
-	if err != nil {
-		respondWithError(w, 401, "Unauthorized")
+	// Chirps are stored in NFC so the same text always has the same bytes,
+	// which the duplicate checks and keyword filters rely on.
+	params.Body = textlen.Normalize(params.Body)
+	if length := aCfg.chirpLength.Length(params.Body); length > aCfg.chirpMaxLength {
+		respondWithJson(w, 400, struct {
+			Error  string `json:"error"`
+			Length int    `json:"length"`
+			Limit  int    `json:"limit"`
+			Over   int    `json:"over"`
+		}{
+			Error:  "chirp too long",
+			Length: length,
+			Limit:  aCfg.chirpMaxLength,
+			Over:   length - aCfg.chirpMaxLength,
+		})
 		return
 	}
 
-	if strings.Count(token, ".") != 2 {
-		respondWithError(w, 401, "Unauthorized")
+	contentWarning, sensitive, ok := normalizeContentWarning(params.ContentWarning, params.Sensitive)
+	if !ok {
+		respondWithError(w, 400, "content warning is too long")
 		return
+	}
 
+	moderated := aCfg.moderation.Check(params.Body)
+	if moderated.Rejected {
+		respondWithError(w, 422, "chirp breaks the content rules")
+		return
 	}
-	validToken, err := auth.ValidateJWT(token, aCfg.secret)
-	log.Println(validToken)
+
+	validToken, _ := userIDFromContext(r.Context())
+
+	user, err := aCfg.db.GetUserById(r.Context(), validToken)
 	if err != nil {
 		respondWithError(w, 401, "Unauthorized")
 		return
 	}
+	if !user.EmailVerifiedAt.Valid {
+		respondWithError(w, 403, "verify your email address before chirping")
+		return
+	}
 
-	dbParams := database.CreateChirpParams{
-		Body:   cleanedBody,
-		UserID: validToken,
+	verdict := aCfg.checkSpam(r, user, moderated.Body)
+	switch verdict.Verdict {
+	case spam.VerdictThrottle:
+		respondWithError(w, 429, "you are posting too fast, slow down")
+		return
+	case spam.VerdictReject:
+		respondWithError(w, 422, "chirp looks like spam")
+		return
 	}
+
+	dbParams := database.CreateChirpParams{
+		Body:           moderated.Body,
+		UserID:         validToken,
+		NeedsReview:    moderated.Flagged || verdict.Verdict == spam.VerdictHold,
+		ContentWarning: contentWarning,
+		Sensitive:      sensitive,
+	}
+	var reasons []string
+	if moderated.Flagged {
+		reasons = append(reasons, "matched moderation rules: "+matchedTerms(moderated, moderation.ActionFlag))
+	}
+	// Held chirps stay hidden until a moderator approves them.
+	if verdict.Verdict == spam.VerdictHold {
+		reasons = append(reasons, "held as likely spam: "+strings.Join(verdict.Score.Reasons, ", "))
+		dbParams.HiddenAt = sql.NullTime{Valid: true, Time: time.Now().UTC()}
+	}
+	dbParams.ReviewReason = strings.Join(reasons, "; ")
 	log.Println(dbParams)
 	chirp, err := aCfg.db.CreateChirp(r.Context(), dbParams)
 
@@ -152,14 +226,24 @@ func (aCfg *apiConfig) handleChirpCreate(w http.ResponseWriter, r *http.Request)
 		respondWithError(w, 500, "database failed to create chirp")
 		return
 	}
-
-	resp := Chirp{
-		Id:        chirp.ID,
-		CreatedAt: chirp.CreatedAt,
-		UpdatedAt: chirp.UpdatedAt,
-		Body:      chirp.Body,
-		User_id:   chirp.UserID,
+	if chirp.NeedsReview {
+		_, err := aCfg.db.CreateReport(r.Context(), database.CreateReportParams{
+			ReportedUserID: chirp.UserID,
+			ChirpID:        uuid.NullUUID{Valid: true, UUID: chirp.ID},
+			ChirpBody:      chirp.Body,
+			Reason:         reportReasonAutomated,
+			Details:        chirp.ReviewReason,
+			Kind:           reportKindChirp,
+		})
+		if err != nil {
+			log.Printf("failed to queue chirp %s for review: %v", chirp.ID, err)
+		}
 	}
-	respondWithJson(w, 201, resp)
+
+	aCfg.storeChirpLinks(r, chirp)
+
+	resp := []Chirp{toChirp(chirp)}
+	aCfg.decorateChirps(r, resp)
+	respondWithJson(w, 201, resp[0])
 
 }
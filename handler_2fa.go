@@ -0,0 +1,252 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/anton-jj/chripy/internal/auth"
+	"github.com/anton-jj/chripy/internal/database"
+)
+
+const (
+	mfaChallengeTTL   = 5 * time.Minute
+	recoveryCodeCount = 10
+)
+
+type mfaChallenge struct {
+	MFARequired bool   `json:"mfa_required"`
+	MFAToken    string `json:"mfa_token"`
+}
+
+func (aCfg *apiConfig) handleTOTPSetup(w http.ResponseWriter, r *http.Request) {
+	userID, _ := userIDFromContext(r.Context())
+
+	user, err := aCfg.db.GetUserById(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, 404, "user not found")
+		return
+	}
+	if user.TotpEnabledAt.Valid {
+		respondWithError(w, 409, "two-factor authentication is already enabled")
+		return
+	}
+
+	secret, err := auth.MakeTOTPSecret()
+	if err != nil {
+		respondWithError(w, 500, "could not create totp secret")
+		return
+	}
+	err = aCfg.db.SetTOTPSecret(r.Context(), database.SetTOTPSecretParams{
+		TotpSecret: sql.NullString{Valid: true, String: secret},
+		ID:         user.ID,
+	})
+	if err != nil {
+		respondWithError(w, 500, "Failed to update database")
+		return
+	}
+
+	respondWithJson(w, 200, struct {
+		Secret     string `json:"secret"`
+		OtpauthURI string `json:"otpauth_uri"`
+	}{
+		Secret:     secret,
+		OtpauthURI: auth.TOTPURI("Chirpy", user.Email, secret),
+	})
+}
+
+func (aCfg *apiConfig) handleTOTPConfirm(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	type parameters struct {
+		Code string `json:"code"`
+	}
+
+	var params parameters
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+		respondWithError(w, 400, "invalid json format")
+		return
+	}
+
+	userID, _ := userIDFromContext(r.Context())
+
+	user, err := aCfg.db.GetUserById(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, 404, "user not found")
+		return
+	}
+	if user.TotpEnabledAt.Valid {
+		respondWithError(w, 409, "two-factor authentication is already enabled")
+		return
+	}
+	if !user.TotpSecret.Valid {
+		respondWithError(w, 400, "two-factor setup has not been started")
+		return
+	}
+	if !aCfg.checkTOTP(r, user, params.Code) {
+		respondWithError(w, 401, "invalid code")
+		return
+	}
+
+	codes := make([]string, 0, recoveryCodeCount)
+	err = aCfg.db.DeleteRecoveryCodes(r.Context(), user.ID)
+	if err != nil {
+		respondWithError(w, 500, "Failed to update database")
+		return
+	}
+	for i := 0; i < recoveryCodeCount; i++ {
+		code, err := auth.MakeRecoveryCode()
+		if err != nil {
+			respondWithError(w, 500, "could not create recovery codes")
+			return
+		}
+		hash, err := auth.HashPassword(code)
+		if err != nil {
+			respondWithError(w, 500, "could not create recovery codes")
+			return
+		}
+		err = aCfg.db.CreateRecoveryCode(r.Context(), database.CreateRecoveryCodeParams{
+			UserID:   user.ID,
+			CodeHash: hash,
+		})
+		if err != nil {
+			respondWithError(w, 500, "Failed to update database")
+			return
+		}
+		codes = append(codes, code)
+	}
+
+	err = aCfg.db.EnableTOTP(r.Context(), user.ID)
+	if err != nil {
+		respondWithError(w, 500, "Failed to update database")
+		return
+	}
+
+	respondWithJson(w, 200, struct {
+		RecoveryCodes []string `json:"recovery_codes"`
+	}{RecoveryCodes: codes})
+}
+
+func (aCfg *apiConfig) handleTOTPDisable(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	type parameters struct {
+		Code string `json:"code"`
+	}
+
+	var params parameters
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+		respondWithError(w, 400, "invalid json format")
+		return
+	}
+
+	userID, _ := userIDFromContext(r.Context())
+
+	user, err := aCfg.db.GetUserById(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, 404, "user not found")
+		return
+	}
+	if !user.TotpEnabledAt.Valid {
+		respondWithError(w, 400, "two-factor authentication is not enabled")
+		return
+	}
+	if !aCfg.checkTOTP(r, user, params.Code) {
+		respondWithError(w, 401, "invalid code")
+		return
+	}
+
+	if err := aCfg.db.DisableTOTP(r.Context(), user.ID); err != nil {
+		respondWithError(w, 500, "Failed to update database")
+		return
+	}
+	if err := aCfg.db.DeleteRecoveryCodes(r.Context(), user.ID); err != nil {
+		respondWithError(w, 500, "Failed to update database")
+		return
+	}
+	respondWithJson(w, 204, nil)
+}
+
+// handleLoginTOTP is the second step of a login for accounts with two-factor
+// authentication: it trades the mfa_token from handleLogin plus a TOTP or
+// recovery code for a full session.
+func (aCfg *apiConfig) handleLoginTOTP(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	type parameters struct {
+		MFAToken     string `json:"mfa_token"`
+		Code         string `json:"code"`
+		RecoveryCode string `json:"recovery_code"`
+	}
+
+	var params parameters
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+		respondWithError(w, 400, "invalid json format")
+		return
+	}
+
+	userID, err := auth.ValidateMFAToken(params.MFAToken, aCfg.secret)
+	if err != nil {
+		respondWithError(w, 401, "Unauthorized")
+		return
+	}
+	user, err := aCfg.db.GetUserById(r.Context(), userID)
+	if err != nil || !user.TotpEnabledAt.Valid {
+		respondWithError(w, 401, "Unauthorized")
+		return
+	}
+
+	if wait := aCfg.loginRetryAfter(r, user.Email); wait > 0 {
+		respondTooManyAttempts(w, wait)
+		return
+	}
+
+	var ok bool
+	if params.RecoveryCode != "" {
+		ok = aCfg.useRecoveryCode(r, user, params.RecoveryCode)
+	} else {
+		ok = aCfg.checkTOTP(r, user, params.Code)
+	}
+	if !ok {
+		aCfg.loginFailed(r, user.Email)
+		respondWithError(w, 401, "invalid code")
+		return
+	}
+	aCfg.loginSucceeded(r, user.Email)
+
+	aCfg.respondWithSession(w, r, user)
+}
+
+// checkTOTP validates code for user and burns its time step, so a code that was
+// seen once (for example by a shoulder surfer) cannot be replayed.
+func (aCfg *apiConfig) checkTOTP(r *http.Request, user database.User, code string) bool {
+	if !user.TotpSecret.Valid {
+		return false
+	}
+	step, ok := auth.ValidateTOTP(code, user.TotpSecret.String, time.Now())
+	if !ok {
+		return false
+	}
+	n, err := aCfg.db.UpdateTOTPLastStep(r.Context(), database.UpdateTOTPLastStepParams{
+		TotpLastStep: sql.NullInt64{Valid: true, Int64: step},
+		ID:           user.ID,
+	})
+	return err == nil && n == 1
+}
+
+func (aCfg *apiConfig) useRecoveryCode(r *http.Request, user database.User, code string) bool {
+	codes, err := aCfg.db.GetUnusedRecoveryCodes(r.Context(), user.ID)
+	if err != nil {
+		return false
+	}
+	for _, c := range codes {
+		match, err := auth.CheckPasswordHash(code, c.CodeHash)
+		if err != nil || !match {
+			continue
+		}
+		n, err := aCfg.db.UseRecoveryCode(r.Context(), c.ID)
+		return err == nil && n == 1
+	}
+	return false
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/anton-jj/chripy/internal/auth"
+	"github.com/anton-jj/chripy/internal/database"
+)
+
+// A forced password reset has to hold on every way into a session, not just
+// the password login.
+func TestLoginTOTPRefusesForcedReset(t *testing.T) {
+	aCfg := testAPIConfig(t)
+	ctx := context.Background()
+	user := createTestUser(t, aCfg, roleUser)
+
+	secret, err := auth.MakeTOTPSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := aCfg.db.SetTOTPSecret(ctx, database.SetTOTPSecretParams{
+		TotpSecret: sql.NullString{Valid: true, String: secret},
+		ID:         user.ID,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := aCfg.db.EnableTOTP(ctx, user.ID); err != nil {
+		t.Fatal(err)
+	}
+	codeHash, err := auth.HashPassword("recovery-code")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := aCfg.db.CreateRecoveryCode(ctx, database.CreateRecoveryCodeParams{UserID: user.ID, CodeHash: codeHash}); err != nil {
+		t.Fatal(err)
+	}
+	if err := aCfg.db.RequirePasswordReset(ctx, user.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	mfaToken, err := auth.MakeMFAToken(user.ID, aCfg.secret, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rec := serve(t, aCfg, "POST /api/login/2fa", aCfg.handleLoginTOTP,
+		"POST", "/api/login/2fa", `{"mfa_token":"`+mfaToken+`","recovery_code":"recovery-code"}`, nil)
+	if rec.Code != 403 {
+		t.Errorf("got %d, want 403: %s", rec.Code, rec.Body)
+	}
+}
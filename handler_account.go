@@ -0,0 +1,233 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/anton-jj/chripy/internal/audit"
+	"github.com/anton-jj/chripy/internal/auth"
+	"github.com/anton-jj/chripy/internal/database"
+	"github.com/anton-jj/chripy/internal/mailer"
+	"github.com/google/uuid"
+)
+
+const accountPurgeInterval = time.Hour
+
+// handleDeleteUser deletes the signed in user once they have proven it is
+// really them. With a grace period the account is only marked for deletion and
+// can be restored until purgeDeletedAccounts gets to it; chirps, tokens and
+// everything else go with the user row through ON DELETE CASCADE.
+func (aCfg *apiConfig) handleDeleteUser(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	type parameters struct {
+		Password string `json:"password"`
+		Code     string `json:"code"`
+	}
+
+	var params parameters
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+		respondWithError(w, 400, "invalid json format")
+		return
+	}
+
+	userID, _ := userIDFromContext(r.Context())
+	user, err := aCfg.db.GetUserById(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, 404, "user not found")
+		return
+	}
+
+	if wait := aCfg.loginRetryAfter(r, user.Email); wait > 0 {
+		respondTooManyAttempts(w, wait)
+		return
+	}
+	match, err := auth.CheckPasswordHash(params.Password, user.HashedPassword)
+	if err != nil || !match || (user.TotpEnabledAt.Valid && !aCfg.checkTOTP(r, user, params.Code)) {
+		aCfg.loginFailed(r, user.Email)
+		respondWithError(w, 401, "Unauthorized")
+		return
+	}
+	aCfg.loginSucceeded(r, user.Email)
+
+	if aCfg.deletionGracePeriod <= 0 {
+		if err := aCfg.db.DeleteUser(r.Context(), user.ID); err != nil {
+			respondWithError(w, 500, "failed to delete user")
+			return
+		}
+		aCfg.recordAudit(r, audit.ActionUserDeleted, user.ID, user.ID, nil)
+		respondWithJson(w, 204, nil)
+		return
+	}
+
+	deleteAfter := time.Now().UTC().Add(aCfg.deletionGracePeriod)
+	err = aCfg.db.ScheduleUserDeletion(r.Context(), database.ScheduleUserDeletionParams{
+		DeleteAfter: sql.NullTime{Valid: true, Time: deleteAfter},
+		ID:          user.ID,
+	})
+	if err != nil {
+		respondWithError(w, 500, "Failed to update database")
+		return
+	}
+	if err := aCfg.db.RevokeAllRefreshTokensForUser(r.Context(), user.ID); err != nil {
+		respondWithError(w, 500, "Failed to update database")
+		return
+	}
+	aCfg.recordAudit(r, audit.ActionUserDeleted, user.ID, user.ID, map[string]interface{}{"delete_after": deleteAfter})
+	err = aCfg.mailer.Send(r.Context(), mailer.Message{
+		To:      user.Email,
+		Subject: "Your Chirpy account will be deleted",
+		Body: fmt.Sprintf("Your Chirpy account is scheduled for deletion on %s.\n\nIf you change your mind, log in and restore it before then.\n",
+			deleteAfter.Format(time.RFC1123)),
+	})
+	if err != nil {
+		log.Printf("failed to send deletion notice to %s: %v", user.ID, err)
+	}
+
+	respondWithJson(w, 202, struct {
+		DeleteAfter time.Time `json:"delete_after"`
+	}{DeleteAfter: deleteAfter})
+}
+
+func (aCfg *apiConfig) handleRestoreUser(w http.ResponseWriter, r *http.Request) {
+	userID, _ := userIDFromContext(r.Context())
+
+	n, err := aCfg.db.CancelUserDeletion(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, 500, "Failed to update database")
+		return
+	}
+	if n == 0 {
+		respondWithError(w, 400, "account is not scheduled for deletion")
+		return
+	}
+	respondWithJson(w, 204, nil)
+}
+
+// purgeDeletedAccounts deletes accounts whose grace period has run out.
+func (aCfg *apiConfig) purgeDeletedAccounts(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		n, err := aCfg.db.DeleteExpiredUsers(ctx)
+		if err != nil {
+			log.Printf("failed to purge deleted accounts: %v", err)
+		} else if n > 0 {
+			log.Printf("purged %d deleted accounts", n)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+type exportChirp struct {
+	ID             uuid.UUID `json:"id"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+	Body           string    `json:"body"`
+	ContentWarning string    `json:"content_warning,omitempty"`
+	Sensitive      bool      `json:"sensitive"`
+}
+
+type sessionStruct struct {
+	CreatedAt time.Time  `json:"created_at"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	RevokedAt *time.Time `json:"revoked_at"`
+}
+
+// handleExportUser hands the signed in user a ZIP with everything we keep
+// about them. Secrets such as password hashes and token values are left out.
+func (aCfg *apiConfig) handleExportUser(w http.ResponseWriter, r *http.Request) {
+	userID, _ := userIDFromContext(r.Context())
+
+	user, err := aCfg.db.GetUserById(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, 404, "user not found")
+		return
+	}
+	chirps, err := aCfg.db.GetChirpsByUser(r.Context(), user.ID)
+	if err != nil {
+		respondWithError(w, 500, "failed to get chirps")
+		return
+	}
+	tokens, err := aCfg.db.GetRefreshTokensByUser(r.Context(), user.ID)
+	if err != nil {
+		respondWithError(w, 500, "failed to get sessions")
+		return
+	}
+
+	profile := struct {
+		userStruct
+		TwoFactorEnabled bool       `json:"two_factor_enabled"`
+		DeleteAfter      *time.Time `json:"delete_after,omitempty"`
+	}{
+		userStruct:       toUserStruct(user),
+		TwoFactorEnabled: user.TotpEnabledAt.Valid,
+	}
+	if user.DeleteAfter.Valid {
+		profile.DeleteAfter = &user.DeleteAfter.Time
+	}
+
+	exportedChirps := []exportChirp{}
+	for _, c := range chirps {
+		exportedChirps = append(exportedChirps, exportChirp{
+			ID:             c.ID,
+			CreatedAt:      c.CreatedAt,
+			UpdatedAt:      c.UpdatedAt,
+			Body:           c.Body,
+			ContentWarning: c.ContentWarning,
+			Sensitive:      c.Sensitive,
+		})
+	}
+	sessions := []sessionStruct{}
+	for _, t := range tokens {
+		s := sessionStruct{CreatedAt: t.CreatedAt, ExpiresAt: t.ExpiresAt}
+		if t.RevokedAt.Valid {
+			s.RevokedAt = &t.RevokedAt.Time
+		}
+		sessions = append(sessions, s)
+	}
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	files := []struct {
+		name string
+		data interface{}
+	}{
+		{"profile.json", profile},
+		{"chirps.json", exportedChirps},
+		{"sessions.json", sessions},
+	}
+	for _, f := range files {
+		fw, err := zw.Create(f.name)
+		if err != nil {
+			respondWithError(w, 500, "failed to build export")
+			return
+		}
+		enc := json.NewEncoder(fw)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(f.data); err != nil {
+			respondWithError(w, 500, "failed to build export")
+			return
+		}
+	}
+	if err := zw.Close(); err != nil {
+		respondWithError(w, 500, "failed to build export")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="chirpy-export-%s.zip"`, user.ID))
+	w.WriteHeader(200)
+	w.Write(buf.Bytes())
+}
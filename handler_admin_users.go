@@ -0,0 +1,322 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/anton-jj/chripy/internal/audit"
+	"github.com/anton-jj/chripy/internal/database"
+	"github.com/google/uuid"
+)
+
+const (
+	adminPageSize    = 50
+	adminMaxPageSize = 200
+)
+
+// likeEscaper makes a search term match itself in a LIKE pattern, escaping
+// with backslash, the Postgres default.
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// adminUserStruct is the staff view of a user, with the account state that
+// is hidden from the user's own profile.
+type adminUserStruct struct {
+	userStruct
+	TwoFactorEnabled  bool       `json:"two_factor_enabled"`
+	SuspendedAt       *time.Time `json:"suspended_at"`
+	SuspensionReason  string     `json:"suspension_reason,omitempty"`
+	LimitedAt         *time.Time `json:"limited_at"`
+	LimitReason       string     `json:"limit_reason,omitempty"`
+	MustResetPassword bool       `json:"must_reset_password"`
+	DeleteAfter       *time.Time `json:"delete_after"`
+}
+
+func toAdminUserStruct(user database.User) adminUserStruct {
+	resp := adminUserStruct{
+		userStruct:        toUserStruct(user),
+		TwoFactorEnabled:  user.TotpEnabledAt.Valid,
+		SuspensionReason:  user.SuspensionReason,
+		LimitReason:       user.LimitReason,
+		MustResetPassword: user.MustResetPassword,
+	}
+	if user.SuspendedAt.Valid {
+		resp.SuspendedAt = &user.SuspendedAt.Time
+	}
+	if user.LimitedAt.Valid {
+		resp.LimitedAt = &user.LimitedAt.Time
+	}
+	if user.DeleteAfter.Valid {
+		resp.DeleteAfter = &user.DeleteAfter.Time
+	}
+	return resp
+}
+
+// handleAdminListUsers lists users oldest first. ?q= filters on emails that
+// contain it literally, and ?limit= and ?offset= page through the result.
+func (aCfg *apiConfig) handleAdminListUsers(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	limit, err := queryInt(query.Get("limit"), adminPageSize)
+	if err != nil || limit < 1 || limit > adminMaxPageSize {
+		respondWithError(w, 400, "invalid limit")
+		return
+	}
+	offset, err := queryInt(query.Get("offset"), 0)
+	if err != nil || offset < 0 {
+		respondWithError(w, 400, "invalid offset")
+		return
+	}
+	search := likeEscaper.Replace(query.Get("q"))
+
+	users, err := aCfg.db.ListUsers(r.Context(), database.ListUsersParams{
+		Search:     search,
+		MaxResults: int32(limit),
+		Skip:       int32(offset),
+	})
+	if err != nil {
+		respondWithError(w, 500, "failed to get users")
+		return
+	}
+	total, err := aCfg.db.CountUsers(r.Context(), search)
+	if err != nil {
+		respondWithError(w, 500, "failed to get users")
+		return
+	}
+
+	resp := struct {
+		Users  []adminUserStruct `json:"users"`
+		Total  int64             `json:"total"`
+		Limit  int               `json:"limit"`
+		Offset int               `json:"offset"`
+	}{Users: []adminUserStruct{}, Total: total, Limit: limit, Offset: offset}
+	for _, u := range users {
+		resp.Users = append(resp.Users, toAdminUserStruct(u))
+	}
+	respondWithJson(w, 200, resp)
+}
+
+func (aCfg *apiConfig) handleAdminGetUser(w http.ResponseWriter, r *http.Request) {
+	user, ok := aCfg.adminTargetUser(w, r)
+	if !ok {
+		return
+	}
+	respondWithJson(w, 200, toAdminUserStruct(user))
+}
+
+func (aCfg *apiConfig) handleAdminUserSessions(w http.ResponseWriter, r *http.Request) {
+	user, ok := aCfg.adminTargetUser(w, r)
+	if !ok {
+		return
+	}
+	tokens, err := aCfg.db.GetRefreshTokensByUser(r.Context(), user.ID)
+	if err != nil {
+		respondWithError(w, 500, "failed to get sessions")
+		return
+	}
+	sessions := []sessionStruct{}
+	for _, t := range tokens {
+		s := sessionStruct{CreatedAt: t.CreatedAt, ExpiresAt: t.ExpiresAt}
+		if t.RevokedAt.Valid {
+			s.RevokedAt = &t.RevokedAt.Time
+		}
+		sessions = append(sessions, s)
+	}
+	respondWithJson(w, 200, sessions)
+}
+
+func (aCfg *apiConfig) handleAdminUserChirps(w http.ResponseWriter, r *http.Request) {
+	user, ok := aCfg.adminTargetUser(w, r)
+	if !ok {
+		return
+	}
+	chirps, err := aCfg.db.GetChirpsByUser(r.Context(), user.ID)
+	if err != nil {
+		respondWithError(w, 500, "failed to get chirps")
+		return
+	}
+	resp := []Chirp{}
+	for _, c := range chirps {
+		resp = append(resp, toChirp(c))
+	}
+	aCfg.decorateChirps(r, resp)
+	respondWithJson(w, 200, resp)
+}
+
+func (aCfg *apiConfig) handleAdminSuspendUser(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	type parameters struct {
+		Reason string `json:"reason"`
+	}
+
+	var params parameters
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+		respondWithError(w, 400, "invalid json format")
+		return
+	}
+
+	user, ok := aCfg.adminTargetUser(w, r)
+	if !ok {
+		return
+	}
+	if adminID, _ := userIDFromContext(r.Context()); adminID == user.ID {
+		respondWithError(w, 400, "you cannot suspend yourself")
+		return
+	}
+
+	err := aCfg.db.SuspendUser(r.Context(), database.SuspendUserParams{
+		SuspensionReason: params.Reason,
+		ID:               user.ID,
+	})
+	if err != nil {
+		respondWithError(w, 500, "Failed to update database")
+		return
+	}
+	if err := aCfg.revokeAllTokens(r, user.ID); err != nil {
+		respondWithError(w, 500, "Failed to update database")
+		return
+	}
+	aCfg.recordAdminAudit(r, audit.ActionAdminSuspend, user.ID, map[string]interface{}{"reason": params.Reason})
+	respondWithJson(w, 204, nil)
+}
+
+func (aCfg *apiConfig) handleAdminUnsuspendUser(w http.ResponseWriter, r *http.Request) {
+	user, ok := aCfg.adminTargetUser(w, r)
+	if !ok {
+		return
+	}
+	if err := aCfg.db.UnsuspendUser(r.Context(), user.ID); err != nil {
+		respondWithError(w, 500, "Failed to update database")
+		return
+	}
+	aCfg.recordAdminAudit(r, audit.ActionAdminUnsuspend, user.ID, nil)
+	respondWithJson(w, 204, nil)
+}
+
+// handleAdminLimitUser shadowbans a user: they can keep chirping and see
+// their own chirps as usual, but nobody else is shown them.
+func (aCfg *apiConfig) handleAdminLimitUser(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	type parameters struct {
+		Reason string `json:"reason"`
+	}
+
+	var params parameters
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+		respondWithError(w, 400, "invalid json format")
+		return
+	}
+
+	user, ok := aCfg.adminTargetUser(w, r)
+	if !ok {
+		return
+	}
+	if moderatorID, _ := userIDFromContext(r.Context()); moderatorID == user.ID {
+		respondWithError(w, 400, "you cannot limit yourself")
+		return
+	}
+
+	err := aCfg.db.LimitUser(r.Context(), database.LimitUserParams{
+		LimitReason: params.Reason,
+		ID:          user.ID,
+	})
+	if err != nil {
+		respondWithError(w, 500, "Failed to update database")
+		return
+	}
+	aCfg.recordAdminAudit(r, audit.ActionAdminLimit, user.ID, map[string]interface{}{"reason": params.Reason})
+	respondWithJson(w, 204, nil)
+}
+
+func (aCfg *apiConfig) handleAdminUnlimitUser(w http.ResponseWriter, r *http.Request) {
+	user, ok := aCfg.adminTargetUser(w, r)
+	if !ok {
+		return
+	}
+	if err := aCfg.db.UnlimitUser(r.Context(), user.ID); err != nil {
+		respondWithError(w, 500, "Failed to update database")
+		return
+	}
+	aCfg.recordAdminAudit(r, audit.ActionAdminUnlimit, user.ID, nil)
+	respondWithJson(w, 204, nil)
+}
+
+// handleAdminForcePasswordReset blocks password logins until the user picks a
+// new password through the emailed reset link, and ends their sessions.
+func (aCfg *apiConfig) handleAdminForcePasswordReset(w http.ResponseWriter, r *http.Request) {
+	user, ok := aCfg.adminTargetUser(w, r)
+	if !ok {
+		return
+	}
+	if err := aCfg.db.RequirePasswordReset(r.Context(), user.ID); err != nil {
+		respondWithError(w, 500, "Failed to update database")
+		return
+	}
+	if err := aCfg.revokeAllTokens(r, user.ID); err != nil {
+		respondWithError(w, 500, "Failed to update database")
+		return
+	}
+	aCfg.recordAdminAudit(r, audit.ActionAdminForceReset, user.ID, nil)
+	if err := aCfg.sendPasswordReset(r.Context(), user.Email); err != nil {
+		respondWithError(w, 500, "failed to send reset email")
+		return
+	}
+	respondWithJson(w, 204, nil)
+}
+
+func (aCfg *apiConfig) handleAdminRevokeTokens(w http.ResponseWriter, r *http.Request) {
+	user, ok := aCfg.adminTargetUser(w, r)
+	if !ok {
+		return
+	}
+	if err := aCfg.revokeAllTokens(r, user.ID); err != nil {
+		respondWithError(w, 500, "Failed to update database")
+		return
+	}
+	aCfg.recordAdminAudit(r, audit.ActionAdminRevokeTokens, user.ID, nil)
+	respondWithJson(w, 204, nil)
+}
+
+// recordAdminAudit records an action the signed in staff member took on target.
+func (aCfg *apiConfig) recordAdminAudit(r *http.Request, action string, target uuid.UUID, metadata map[string]interface{}) {
+	adminID, _ := userIDFromContext(r.Context())
+	aCfg.recordAudit(r, action, adminID, target, metadata)
+}
+
+// revokeAllTokens ends the user's refresh tokens and OAuth access tokens and
+// deletes their API keys. Session JWTs can't be revoked, but they are short
+// lived and the auth middleware stops honouring them once the account is
+// suspended.
+func (aCfg *apiConfig) revokeAllTokens(r *http.Request, userID uuid.UUID) error {
+	if err := aCfg.db.RevokeAllRefreshTokensForUser(r.Context(), userID); err != nil {
+		return err
+	}
+	if err := aCfg.db.RevokeAllOAuthAccessTokensForUser(r.Context(), userID); err != nil {
+		return err
+	}
+	return aCfg.db.DeleteAPIKeysForUser(r.Context(), userID)
+}
+
+func (aCfg *apiConfig) adminTargetUser(w http.ResponseWriter, r *http.Request) (database.User, bool) {
+	id, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		respondWithError(w, 400, "invalid user id")
+		return database.User{}, false
+	}
+	user, err := aCfg.db.GetUserById(r.Context(), id)
+	if err != nil {
+		respondWithError(w, 404, "user not found")
+		return database.User{}, false
+	}
+	return user, true
+}
+
+func queryInt(v string, fallback int) (int, error) {
+	if v == "" {
+		return fallback, nil
+	}
+	return strconv.Atoi(v)
+}
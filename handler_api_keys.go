@@ -0,0 +1,136 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/anton-jj/chripy/internal/auth"
+	"github.com/anton-jj/chripy/internal/database"
+	"github.com/google/uuid"
+)
+
+type apiKeyStruct struct {
+	ID         uuid.UUID  `json:"id"`
+	CreatedAt  time.Time  `json:"created_at"`
+	Name       string     `json:"name"`
+	Key        string     `json:"key,omitempty"`
+	Scopes     []string   `json:"scopes"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+}
+
+func toAPIKeyStruct(key database.ApiKey) apiKeyStruct {
+	resp := apiKeyStruct{
+		ID:        key.ID,
+		CreatedAt: key.CreatedAt,
+		Name:      key.Name,
+		Scopes:    key.Scopes,
+	}
+	if key.ExpiresAt.Valid {
+		resp.ExpiresAt = &key.ExpiresAt.Time
+	}
+	if key.LastUsedAt.Valid {
+		resp.LastUsedAt = &key.LastUsedAt.Time
+	}
+	return resp
+}
+
+func (aCfg *apiConfig) handleCreateAPIKey(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	type parameters struct {
+		Name      string     `json:"name"`
+		Scopes    []string   `json:"scopes"`
+		ExpiresAt *time.Time `json:"expires_at"`
+	}
+
+	var params parameters
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+		respondWithError(w, 400, "invalid json format")
+		return
+	}
+
+	if params.Name == "" {
+		respondWithError(w, 400, "name cant be empty")
+		return
+	}
+	if len(params.Scopes) == 0 {
+		respondWithError(w, 400, "at least one scope is required")
+		return
+	}
+	for _, scope := range params.Scopes {
+		if !auth.ValidScope(scope) {
+			respondWithError(w, 400, "unknown scope: "+scope)
+			return
+		}
+	}
+	if params.ExpiresAt != nil && params.ExpiresAt.Before(time.Now()) {
+		respondWithError(w, 400, "expires_at must be in the future")
+		return
+	}
+
+	userID, _ := userIDFromContext(r.Context())
+
+	key, err := auth.MakeAPIKey()
+	if err != nil {
+		respondWithError(w, 500, "could not create api key")
+		return
+	}
+
+	keyParams := database.CreateAPIKeyParams{
+		UserID:  userID,
+		Name:    params.Name,
+		KeyHash: auth.HashToken(key),
+		Scopes:  params.Scopes,
+	}
+	if params.ExpiresAt != nil {
+		keyParams.ExpiresAt = sql.NullTime{Valid: true, Time: params.ExpiresAt.UTC()}
+	}
+	row, err := aCfg.db.CreateAPIKey(r.Context(), keyParams)
+	if err != nil {
+		respondWithError(w, 500, "failed to store api key")
+		return
+	}
+
+	resp := toAPIKeyStruct(row)
+	resp.Key = key
+	respondWithJson(w, 201, resp)
+}
+
+func (aCfg *apiConfig) handleListAPIKeys(w http.ResponseWriter, r *http.Request) {
+	userID, _ := userIDFromContext(r.Context())
+
+	keys, err := aCfg.db.GetAPIKeysByUser(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, 500, "failed to get api keys")
+		return
+	}
+
+	resp := []apiKeyStruct{}
+	for _, key := range keys {
+		resp = append(resp, toAPIKeyStruct(key))
+	}
+	respondWithJson(w, 200, resp)
+}
+
+func (aCfg *apiConfig) handleDeleteAPIKey(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(r.PathValue("keyID"))
+	if err != nil {
+		respondWithError(w, 400, "invalid key id")
+		return
+	}
+
+	userID, _ := userIDFromContext(r.Context())
+
+	err = aCfg.db.DeleteAPIKey(r.Context(), database.DeleteAPIKeyParams{
+		ID:     id,
+		UserID: userID,
+	})
+	if err != nil {
+		respondWithError(w, 500, "failed to delete api key")
+		return
+	}
+	respondWithJson(w, 204, nil)
+}
@@ -0,0 +1,257 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/anton-jj/chripy/internal/database"
+	"github.com/anton-jj/chripy/internal/moderation"
+	"github.com/google/uuid"
+)
+
+// Where a keyword filter applies. Only the public feed exists so far; the
+// others are accepted so clients can save them ahead of those features.
+const (
+	filterContextHome          = "home"
+	filterContextPublic        = "public"
+	filterContextNotifications = "notifications"
+	filterContextSearch        = "search"
+)
+
+var filterContexts = []string{filterContextHome, filterContextPublic, filterContextNotifications, filterContextSearch}
+
+const (
+	maxKeywordFilters      = 200
+	maxKeywordFilterLength = 100
+)
+
+type keywordFilterStruct struct {
+	ID        uuid.UUID  `json:"id"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+	Phrase    string     `json:"phrase"`
+	WholeWord bool       `json:"whole_word"`
+	Contexts  []string   `json:"contexts"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+func toKeywordFilterStruct(f database.KeywordFilter) keywordFilterStruct {
+	resp := keywordFilterStruct{
+		ID:        f.ID,
+		CreatedAt: f.CreatedAt,
+		UpdatedAt: f.UpdatedAt,
+		Phrase:    f.Phrase,
+		WholeWord: f.WholeWord,
+		Contexts:  f.Contexts,
+	}
+	if f.ExpiresAt.Valid {
+		resp.ExpiresAt = &f.ExpiresAt.Time
+	}
+	return resp
+}
+
+// keywordFilterParams is the body of both creating and replacing a filter.
+// whole_word defaults to true and contexts to every context.
+type keywordFilterParams struct {
+	Phrase    string     `json:"phrase"`
+	WholeWord *bool      `json:"whole_word"`
+	Contexts  []string   `json:"contexts"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+// decodeKeywordFilter reads and validates a filter from the request body. On
+// failure it returns the message to send back.
+func decodeKeywordFilter(r *http.Request) (database.KeywordFilter, string) {
+	var params keywordFilterParams
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+		return database.KeywordFilter{}, "invalid json format"
+	}
+
+	phrase := strings.TrimSpace(params.Phrase)
+	if moderation.Normalize(phrase) == "" {
+		return database.KeywordFilter{}, "phrase needs at least one letter or digit"
+	}
+	if utf8.RuneCountInString(phrase) > maxKeywordFilterLength {
+		return database.KeywordFilter{}, "phrase is too long"
+	}
+	filter := database.KeywordFilter{Phrase: phrase, WholeWord: true, Contexts: filterContexts}
+	if params.WholeWord != nil {
+		filter.WholeWord = *params.WholeWord
+	}
+	if params.Contexts != nil {
+		if len(params.Contexts) == 0 {
+			return database.KeywordFilter{}, "at least one context is required"
+		}
+		var contexts []string
+		for _, c := range params.Contexts {
+			if !slices.Contains(filterContexts, c) {
+				return database.KeywordFilter{}, "unknown context: " + c
+			}
+			if !slices.Contains(contexts, c) {
+				contexts = append(contexts, c)
+			}
+		}
+		filter.Contexts = contexts
+	}
+	if params.ExpiresAt != nil {
+		if params.ExpiresAt.Before(time.Now()) {
+			return database.KeywordFilter{}, "expires_at must be in the future"
+		}
+		filter.ExpiresAt = sql.NullTime{Valid: true, Time: params.ExpiresAt.UTC()}
+	}
+	return filter, ""
+}
+
+func (aCfg *apiConfig) handleCreateKeywordFilter(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	filter, msg := decodeKeywordFilter(r)
+	if msg != "" {
+		respondWithError(w, 400, msg)
+		return
+	}
+	userID, _ := userIDFromContext(r.Context())
+
+	count, err := aCfg.db.CountKeywordFilters(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, 500, "failed to get keyword filters")
+		return
+	}
+	if count >= maxKeywordFilters {
+		respondWithError(w, 409, "you have too many keyword filters")
+		return
+	}
+
+	row, err := aCfg.db.CreateKeywordFilter(r.Context(), database.CreateKeywordFilterParams{
+		UserID:    userID,
+		Phrase:    filter.Phrase,
+		WholeWord: filter.WholeWord,
+		Contexts:  filter.Contexts,
+		ExpiresAt: filter.ExpiresAt,
+	})
+	if err != nil {
+		respondWithError(w, 500, "failed to store keyword filter")
+		return
+	}
+	respondWithJson(w, 201, toKeywordFilterStruct(row))
+}
+
+func (aCfg *apiConfig) handleListKeywordFilters(w http.ResponseWriter, r *http.Request) {
+	userID, _ := userIDFromContext(r.Context())
+
+	filters, err := aCfg.db.ListKeywordFilters(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, 500, "failed to get keyword filters")
+		return
+	}
+	resp := []keywordFilterStruct{}
+	for _, f := range filters {
+		resp = append(resp, toKeywordFilterStruct(f))
+	}
+	respondWithJson(w, 200, resp)
+}
+
+// handleReplaceKeywordFilter overwrites a filter with the request body, so
+// leaving out expires_at makes the filter permanent.
+func (aCfg *apiConfig) handleReplaceKeywordFilter(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	id, err := uuid.Parse(r.PathValue("filterID"))
+	if err != nil {
+		respondWithError(w, 400, "invalid filter id")
+		return
+	}
+	filter, msg := decodeKeywordFilter(r)
+	if msg != "" {
+		respondWithError(w, 400, msg)
+		return
+	}
+	userID, _ := userIDFromContext(r.Context())
+
+	row, err := aCfg.db.UpdateKeywordFilter(r.Context(), database.UpdateKeywordFilterParams{
+		Phrase:    filter.Phrase,
+		WholeWord: filter.WholeWord,
+		Contexts:  filter.Contexts,
+		ExpiresAt: filter.ExpiresAt,
+		ID:        id,
+		UserID:    userID,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, 404, "keyword filter not found")
+		return
+	}
+	if err != nil {
+		respondWithError(w, 500, "Failed to update database")
+		return
+	}
+	respondWithJson(w, 200, toKeywordFilterStruct(row))
+}
+
+func (aCfg *apiConfig) handleDeleteKeywordFilter(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(r.PathValue("filterID"))
+	if err != nil {
+		respondWithError(w, 400, "invalid filter id")
+		return
+	}
+	userID, _ := userIDFromContext(r.Context())
+
+	n, err := aCfg.db.DeleteKeywordFilter(r.Context(), database.DeleteKeywordFilterParams{
+		ID:     id,
+		UserID: userID,
+	})
+	if err != nil {
+		respondWithError(w, 500, "failed to delete keyword filter")
+		return
+	}
+	if n == 0 {
+		respondWithError(w, 404, "keyword filter not found")
+		return
+	}
+	respondWithJson(w, 204, nil)
+}
+
+// applyKeywordFilters drops the chirps that match one of the signed in
+// reader's unexpired filters for filterContext. The reader's own chirps are never
+// filtered. If the filters can't be read the list is returned unfiltered.
+func (aCfg *apiConfig) applyKeywordFilters(r *http.Request, filterContext string, chirps []database.Chirp) []database.Chirp {
+	viewer, ok := userIDFromContext(r.Context())
+	if !ok {
+		return chirps
+	}
+	filters, err := aCfg.db.GetActiveKeywordFilters(r.Context(), viewer)
+	if err != nil {
+		log.Printf("failed to get keyword filters for %s: %v", viewer, err)
+		return chirps
+	}
+	filters = slices.DeleteFunc(filters, func(f database.KeywordFilter) bool {
+		return !slices.Contains(f.Contexts, filterContext)
+	})
+	if len(filters) == 0 {
+		return chirps
+	}
+
+	// Phrases and chirps are each normalized once, not once per pair.
+	phrases := make([]moderation.Phrase, len(filters))
+	for i, f := range filters {
+		phrases[i] = moderation.NewPhrase(f.Phrase, f.WholeWord)
+	}
+	return slices.DeleteFunc(chirps, func(c database.Chirp) bool {
+		if c.UserID == viewer {
+			return false
+		}
+		body, warning := moderation.NewText(c.Body), moderation.NewText(c.ContentWarning)
+		for _, p := range phrases {
+			if body.Contains(p) || warning.Contains(p) {
+				return true
+			}
+		}
+		return false
+	})
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/anton-jj/chripy/internal/audit"
+	"github.com/anton-jj/chripy/internal/database"
+	"github.com/anton-jj/chripy/internal/moderation"
+	"github.com/google/uuid"
+)
+
+const moderationReloadInterval = time.Minute
+
+type moderationRuleStruct struct {
+	ID        uuid.UUID `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	Term      string    `json:"term"`
+	Action    string    `json:"action"`
+}
+
+func toModerationRuleStruct(row database.ModerationRule) moderationRuleStruct {
+	return moderationRuleStruct{
+		ID:        row.ID,
+		CreatedAt: row.CreatedAt,
+		UpdatedAt: row.UpdatedAt,
+		Term:      row.Term,
+		Action:    row.Action,
+	}
+}
+
+func (aCfg *apiConfig) handleListModerationRules(w http.ResponseWriter, r *http.Request) {
+	rows, err := aCfg.db.ListModerationRules(r.Context())
+	if err != nil {
+		respondWithError(w, 500, "failed to get moderation rules")
+		return
+	}
+	resp := []moderationRuleStruct{}
+	for _, row := range rows {
+		resp = append(resp, toModerationRuleStruct(row))
+	}
+	respondWithJson(w, 200, resp)
+}
+
+func (aCfg *apiConfig) handleCreateModerationRule(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	var params moderation.Rule
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+		respondWithError(w, 400, "invalid json format")
+		return
+	}
+	if err := params.Validate(); err != nil {
+		respondWithError(w, 400, err.Error())
+		return
+	}
+
+	row, err := aCfg.db.CreateModerationRule(r.Context(), database.CreateModerationRuleParams{
+		Term:   params.Term,
+		Action: string(params.Action),
+	})
+	if err != nil {
+		respondWithError(w, 409, "a rule for that term already exists")
+		return
+	}
+	aCfg.recordAdminAudit(r, audit.ActionAdminRuleCreated, uuid.Nil, map[string]interface{}{
+		"rule_id": row.ID,
+		"term":    row.Term,
+		"action":  row.Action,
+	})
+	aCfg.reloadModeration(r)
+	respondWithJson(w, 201, toModerationRuleStruct(row))
+}
+
+func (aCfg *apiConfig) handleUpdateModerationRule(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	type parameters struct {
+		Action moderation.Action `json:"action"`
+	}
+
+	id, err := uuid.Parse(r.PathValue("ruleID"))
+	if err != nil {
+		respondWithError(w, 400, "invalid rule id")
+		return
+	}
+	var params parameters
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+		respondWithError(w, 400, "invalid json format")
+		return
+	}
+	if !params.Action.Valid() {
+		respondWithError(w, 400, moderation.ErrInvalidRule.Error())
+		return
+	}
+
+	row, err := aCfg.db.UpdateModerationRule(r.Context(), database.UpdateModerationRuleParams{
+		Action: string(params.Action),
+		ID:     id,
+	})
+	if err != nil {
+		respondWithError(w, 404, "rule not found")
+		return
+	}
+	aCfg.recordAdminAudit(r, audit.ActionAdminRuleUpdated, uuid.Nil, map[string]interface{}{
+		"rule_id": row.ID,
+		"term":    row.Term,
+		"action":  row.Action,
+	})
+	aCfg.reloadModeration(r)
+	respondWithJson(w, 200, toModerationRuleStruct(row))
+}
+
+func (aCfg *apiConfig) handleDeleteModerationRule(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(r.PathValue("ruleID"))
+	if err != nil {
+		respondWithError(w, 400, "invalid rule id")
+		return
+	}
+	n, err := aCfg.db.DeleteModerationRule(r.Context(), id)
+	if err != nil {
+		respondWithError(w, 500, "failed to delete rule")
+		return
+	}
+	if n == 0 {
+		respondWithError(w, 404, "rule not found")
+		return
+	}
+	aCfg.recordAdminAudit(r, audit.ActionAdminRuleDeleted, uuid.Nil, map[string]interface{}{"rule_id": id})
+	aCfg.reloadModeration(r)
+	respondWithJson(w, 204, nil)
+}
+
+// handleReloadModeration picks up rule file edits without waiting for the
+// next periodic reload.
+func (aCfg *apiConfig) handleReloadModeration(w http.ResponseWriter, r *http.Request) {
+	if err := aCfg.moderation.Reload(r.Context()); err != nil {
+		respondWithError(w, 500, "failed to reload moderation rules: "+err.Error())
+		return
+	}
+	respondWithJson(w, 204, nil)
+}
+
+// reloadModeration applies a rule change on this instance straight away;
+// other instances catch up on their next periodic reload.
+func (aCfg *apiConfig) reloadModeration(r *http.Request) {
+	if err := aCfg.moderation.Reload(r.Context()); err != nil {
+		log.Printf("moderation: reload failed, keeping the current rules: %v", err)
+	}
+}
+
+func matchedTerms(res moderation.Result, action moderation.Action) string {
+	var terms []string
+	for _, m := range res.Matches {
+		if m.Action == action {
+			terms = append(terms, m.Term)
+		}
+	}
+	return strings.Join(terms, ", ")
+}
@@ -0,0 +1,440 @@
+package main
+
+import (
+	"crypto/subtle"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"html/template"
+	"log"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/anton-jj/chripy/internal/auth"
+	"github.com/anton-jj/chripy/internal/database"
+	"github.com/google/uuid"
+)
+
+const (
+	oauthCodeTTL        = 10 * time.Minute
+	oauthAccessTokenTTL = time.Hour
+)
+
+var consentTemplate = template.Must(template.New("consent").Parse(`<html>
+<body>
+<h1>Authorize {{.ClientName}}</h1>
+<p>{{.ClientName}} wants to act on your behalf with these permissions:</p>
+<ul>
+{{range .Scopes}}<li>{{.}}</li>
+{{end}}</ul>
+{{if .Error}}<p>{{.Error}}</p>{{end}}
+<form method="POST" action="/oauth/authorize">
+{{range $k, $v := .Params}}<input type="hidden" name="{{$k}}" value="{{$v}}">
+{{end}}<label>Email <input type="email" name="email"></label>
+<label>Password <input type="password" name="password"></label>
+<label>Two-factor code (if enabled) <input type="text" name="code" autocomplete="one-time-code"></label>
+<button type="submit" name="action" value="approve">Approve</button>
+<button type="submit" name="action" value="deny">Deny</button>
+</form>
+</body>
+</html>
+`))
+
+type oauthClientStruct struct {
+	ClientID     uuid.UUID `json:"client_id"`
+	CreatedAt    time.Time `json:"created_at"`
+	Name         string    `json:"name"`
+	RedirectURIs []string  `json:"redirect_uris"`
+	ClientSecret string    `json:"client_secret,omitempty"`
+}
+
+// authorizeRequest is a validated /oauth/authorize request.
+type authorizeRequest struct {
+	Client        database.OauthClient
+	RedirectURI   string
+	State         string
+	Scopes        []string
+	CodeChallenge string
+}
+
+func (aCfg *apiConfig) handleCreateOAuthClient(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	type parameters struct {
+		Name         string   `json:"name"`
+		RedirectURIs []string `json:"redirect_uris"`
+		Confidential bool     `json:"confidential"`
+	}
+
+	var params parameters
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+		respondWithError(w, 400, "invalid json format")
+		return
+	}
+
+	if params.Name == "" {
+		respondWithError(w, 400, "name cant be empty")
+		return
+	}
+	if len(params.RedirectURIs) == 0 {
+		respondWithError(w, 400, "at least one redirect uri is required")
+		return
+	}
+	for _, uri := range params.RedirectURIs {
+		u, err := url.Parse(uri)
+		if err != nil || !u.IsAbs() || u.Fragment != "" {
+			respondWithError(w, 400, "invalid redirect uri: "+uri)
+			return
+		}
+	}
+
+	userID, _ := userIDFromContext(r.Context())
+
+	clientParams := database.CreateOAuthClientParams{
+		OwnerID:      userID,
+		Name:         params.Name,
+		RedirectUris: params.RedirectURIs,
+	}
+	var secret string
+	if params.Confidential {
+		var err error
+		secret, err = auth.MakeRefreshToken()
+		if err != nil {
+			respondWithError(w, 500, "could not create client secret")
+			return
+		}
+		clientParams.SecretHash = sql.NullString{Valid: true, String: auth.HashToken(secret)}
+	}
+
+	client, err := aCfg.db.CreateOAuthClient(r.Context(), clientParams)
+	if err != nil {
+		respondWithError(w, 500, "failed to create client")
+		return
+	}
+
+	respondWithJson(w, 201, oauthClientStruct{
+		ClientID:     client.ID,
+		CreatedAt:    client.CreatedAt,
+		Name:         client.Name,
+		RedirectURIs: client.RedirectUris,
+		ClientSecret: secret,
+	})
+}
+
+func (aCfg *apiConfig) handleOAuthAuthorize(w http.ResponseWriter, r *http.Request) {
+	req, ok := aCfg.parseAuthorizeRequest(w, r, r.URL.Query())
+	if !ok {
+		return
+	}
+	renderConsent(w, req, r.URL.Query(), "")
+}
+
+func (aCfg *apiConfig) handleOAuthConsent(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		respondWithError(w, 400, "invalid form")
+		return
+	}
+	req, ok := aCfg.parseAuthorizeRequest(w, r, r.PostForm)
+	if !ok {
+		return
+	}
+
+	if r.PostForm.Get("action") != "approve" {
+		redirectWithError(w, r, req, "access_denied")
+		return
+	}
+
+	email := r.PostForm.Get("email")
+	if aCfg.loginRetryAfter(r, email) > 0 {
+		renderConsent(w, req, r.PostForm, "Too many login attempts, try again later")
+		return
+	}
+	user, err := aCfg.db.GetUserByEmail(r.Context(), email)
+	hash := user.HashedPassword
+	if err != nil {
+		hash = dummyHash()
+	}
+	checked, hashErr := auth.CheckPasswordHash(r.PostForm.Get("password"), hash)
+	if err != nil || hashErr != nil || !checked {
+		aCfg.loginFailed(r, email)
+		renderConsent(w, req, r.PostForm, "Incorrect email or password")
+		return
+	}
+	if user.SuspendedAt.Valid {
+		renderConsent(w, req, r.PostForm, "This account is suspended")
+		return
+	}
+	if user.MustResetPassword {
+		renderConsent(w, req, r.PostForm, "Password reset required, check your email for a reset link")
+		return
+	}
+	if user.TotpEnabledAt.Valid && !aCfg.checkTOTP(r, user, r.PostForm.Get("code")) {
+		aCfg.loginFailed(r, email)
+		renderConsent(w, req, r.PostForm, "Invalid two-factor code")
+		return
+	}
+	aCfg.loginSucceeded(r, email)
+
+	code, err := auth.MakeRefreshToken()
+	if err != nil {
+		redirectWithError(w, r, req, "server_error")
+		return
+	}
+	err = aCfg.db.CreateOAuthCode(r.Context(), database.CreateOAuthCodeParams{
+		CodeHash:      auth.HashToken(code),
+		ClientID:      req.Client.ID,
+		UserID:        user.ID,
+		RedirectUri:   req.RedirectURI,
+		Scopes:        req.Scopes,
+		CodeChallenge: req.CodeChallenge,
+		ExpiresAt:     time.Now().UTC().Add(oauthCodeTTL),
+	})
+	if err != nil {
+		redirectWithError(w, r, req, "server_error")
+		return
+	}
+
+	redirectWithParams(w, r, req, url.Values{"code": {code}})
+}
+
+func (aCfg *apiConfig) handleOAuthToken(w http.ResponseWriter, r *http.Request) {
+	client, err := aCfg.authenticateClient(r)
+	if err != nil {
+		respondWithJson(w, 401, responeError{Error: "invalid_client"})
+		return
+	}
+	if r.PostForm.Get("grant_type") != "authorization_code" {
+		respondWithJson(w, 400, responeError{Error: "unsupported_grant_type"})
+		return
+	}
+
+	code, err := aCfg.db.ConsumeOAuthCode(r.Context(), auth.HashToken(r.PostForm.Get("code")))
+	if err != nil {
+		respondWithJson(w, 400, responeError{Error: "invalid_grant"})
+		return
+	}
+	if code.ClientID != client.ID ||
+		code.RedirectUri != r.PostForm.Get("redirect_uri") ||
+		code.ExpiresAt.Before(time.Now().UTC()) ||
+		!auth.VerifyPKCE(r.PostForm.Get("code_verifier"), code.CodeChallenge) {
+		respondWithJson(w, 400, responeError{Error: "invalid_grant"})
+		return
+	}
+
+	row, err := aCfg.db.CreateOAuthAccessToken(r.Context(), database.CreateOAuthAccessTokenParams{
+		ClientID:  client.ID,
+		UserID:    code.UserID,
+		Scopes:    code.Scopes,
+		ExpiresAt: time.Now().UTC().Add(oauthAccessTokenTTL),
+	})
+	if err != nil {
+		respondWithJson(w, 500, responeError{Error: "server_error"})
+		return
+	}
+	token, err := auth.MakeAccessToken(row.ID, row.UserID, row.ClientID, row.Scopes, aCfg.secret, oauthAccessTokenTTL)
+	if err != nil {
+		respondWithJson(w, 500, responeError{Error: "server_error"})
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	respondWithJson(w, 200, struct {
+		AccessToken string `json:"access_token"`
+		TokenType   string `json:"token_type"`
+		ExpiresIn   int    `json:"expires_in"`
+		Scope       string `json:"scope"`
+	}{
+		AccessToken: token,
+		TokenType:   "Bearer",
+		ExpiresIn:   int(oauthAccessTokenTTL.Seconds()),
+		Scope:       strings.Join(row.Scopes, " "),
+	})
+}
+
+func (aCfg *apiConfig) handleOAuthIntrospect(w http.ResponseWriter, r *http.Request) {
+	client, err := aCfg.authenticateClient(r)
+	if err != nil {
+		respondWithJson(w, 401, responeError{Error: "invalid_client"})
+		return
+	}
+
+	type introspection struct {
+		Active    bool   `json:"active"`
+		Scope     string `json:"scope,omitempty"`
+		ClientID  string `json:"client_id,omitempty"`
+		Sub       string `json:"sub,omitempty"`
+		Exp       int64  `json:"exp,omitempty"`
+		Iat       int64  `json:"iat,omitempty"`
+		TokenType string `json:"token_type,omitempty"`
+	}
+
+	row, err := aCfg.lookupAccessToken(r, r.PostForm.Get("token"))
+	if err != nil || row.ClientID != client.ID || row.RevokedAt.Valid || row.ExpiresAt.Before(time.Now().UTC()) {
+		respondWithJson(w, 200, introspection{Active: false})
+		return
+	}
+
+	respondWithJson(w, 200, introspection{
+		Active:    true,
+		Scope:     strings.Join(row.Scopes, " "),
+		ClientID:  row.ClientID.String(),
+		Sub:       row.UserID.String(),
+		Exp:       row.ExpiresAt.Unix(),
+		Iat:       row.CreatedAt.Unix(),
+		TokenType: "Bearer",
+	})
+}
+
+func (aCfg *apiConfig) handleOAuthRevoke(w http.ResponseWriter, r *http.Request) {
+	client, err := aCfg.authenticateClient(r)
+	if err != nil {
+		respondWithJson(w, 401, responeError{Error: "invalid_client"})
+		return
+	}
+
+	// Unknown or foreign tokens are not an error for the caller (RFC 7009).
+	row, err := aCfg.lookupAccessToken(r, r.PostForm.Get("token"))
+	if err == nil {
+		err = aCfg.db.RevokeOAuthAccessToken(r.Context(), database.RevokeOAuthAccessTokenParams{
+			ID:       row.ID,
+			ClientID: client.ID,
+		})
+		if err != nil {
+			respondWithJson(w, 500, responeError{Error: "server_error"})
+			return
+		}
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// parseAuthorizeRequest validates the authorization request parameters. Until
+// client and redirect_uri check out, errors are shown to the user directly;
+// after that they are sent back to the client through the redirect.
+func (aCfg *apiConfig) parseAuthorizeRequest(w http.ResponseWriter, r *http.Request, values url.Values) (authorizeRequest, bool) {
+	var req authorizeRequest
+
+	clientID, err := uuid.Parse(values.Get("client_id"))
+	if err != nil {
+		respondWithError(w, 400, "invalid client_id")
+		return req, false
+	}
+	client, err := aCfg.db.GetOAuthClient(r.Context(), clientID)
+	if err != nil {
+		respondWithError(w, 400, "unknown client")
+		return req, false
+	}
+	if !slices.Contains(client.RedirectUris, values.Get("redirect_uri")) {
+		respondWithError(w, 400, "redirect_uri is not registered for this client")
+		return req, false
+	}
+	req.Client = client
+	req.RedirectURI = values.Get("redirect_uri")
+	req.State = values.Get("state")
+
+	if values.Get("response_type") != "code" {
+		redirectWithError(w, r, req, "unsupported_response_type")
+		return req, false
+	}
+	req.CodeChallenge = values.Get("code_challenge")
+	if req.CodeChallenge == "" || values.Get("code_challenge_method") != "S256" {
+		redirectWithError(w, r, req, "invalid_request")
+		return req, false
+	}
+	req.Scopes = auth.ParseScopes(values.Get("scope"))
+	if len(req.Scopes) == 0 {
+		redirectWithError(w, r, req, "invalid_scope")
+		return req, false
+	}
+	for _, scope := range req.Scopes {
+		if !auth.ValidScope(scope) {
+			redirectWithError(w, r, req, "invalid_scope")
+			return req, false
+		}
+	}
+	return req, true
+}
+
+// authenticateClient checks client credentials sent with HTTP basic auth or as
+// form fields. Public clients only send their client_id; PKCE protects them.
+func (aCfg *apiConfig) authenticateClient(r *http.Request) (database.OauthClient, error) {
+	if err := r.ParseForm(); err != nil {
+		return database.OauthClient{}, err
+	}
+	id, secret, ok := r.BasicAuth()
+	if !ok {
+		id = r.PostForm.Get("client_id")
+		secret = r.PostForm.Get("client_secret")
+	}
+	clientID, err := uuid.Parse(id)
+	if err != nil {
+		return database.OauthClient{}, err
+	}
+	client, err := aCfg.db.GetOAuthClient(r.Context(), clientID)
+	if err != nil {
+		return database.OauthClient{}, err
+	}
+	if client.SecretHash.Valid &&
+		subtle.ConstantTimeCompare([]byte(auth.HashToken(secret)), []byte(client.SecretHash.String)) != 1 {
+		return database.OauthClient{}, errors.New("invalid client secret")
+	}
+	return client, nil
+}
+
+func (aCfg *apiConfig) lookupAccessToken(r *http.Request, token string) (database.OauthAccessToken, error) {
+	claims, err := auth.ValidateAccessToken(token, aCfg.secret)
+	if err != nil {
+		return database.OauthAccessToken{}, err
+	}
+	tokenID, err := uuid.Parse(claims.ID)
+	if err != nil {
+		return database.OauthAccessToken{}, err
+	}
+	return aCfg.db.GetOAuthAccessToken(r.Context(), tokenID)
+}
+
+func renderConsent(w http.ResponseWriter, req authorizeRequest, values url.Values, message string) {
+	params := map[string]string{}
+	for _, k := range []string{"response_type", "client_id", "redirect_uri", "scope", "state", "code_challenge", "code_challenge_method"} {
+		params[k] = values.Get(k)
+	}
+	w.Header().Set("Content-Type", "text/html; charset=UTF-8")
+	w.Header().Set("X-Frame-Options", "DENY")
+	err := consentTemplate.Execute(w, struct {
+		ClientName string
+		Scopes     []string
+		Params     map[string]string
+		Error      string
+	}{
+		ClientName: req.Client.Name,
+		Scopes:     req.Scopes,
+		Params:     params,
+		Error:      message,
+	})
+	if err != nil {
+		log.Printf("failed to render consent page: %v", err)
+	}
+}
+
+func redirectWithError(w http.ResponseWriter, r *http.Request, req authorizeRequest, code string) {
+	redirectWithParams(w, r, req, url.Values{"error": {code}})
+}
+
+func redirectWithParams(w http.ResponseWriter, r *http.Request, req authorizeRequest, params url.Values) {
+	u, err := url.Parse(req.RedirectURI)
+	if err != nil {
+		respondWithError(w, 400, "invalid redirect uri")
+		return
+	}
+	q := u.Query()
+	for k, v := range params {
+		q[k] = v
+	}
+	if req.State != "" {
+		q.Set("state", req.State)
+	}
+	u.RawQuery = q.Encode()
+	http.Redirect(w, r, u.String(), http.StatusFound)
+}
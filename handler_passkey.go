@@ -0,0 +1,328 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/anton-jj/chripy/internal/auth"
+	"github.com/anton-jj/chripy/internal/database"
+	"github.com/google/uuid"
+)
+
+const (
+	webAuthnChallengeTTL = 5 * time.Minute
+
+	ceremonyRegister = "register"
+	ceremonyLogin    = "login"
+)
+
+var errWebAuthnChallengeExpired = errors.New("webauthn challenge expired")
+
+type passkeyStruct struct {
+	ID         uuid.UUID  `json:"id"`
+	CreatedAt  time.Time  `json:"created_at"`
+	Name       string     `json:"name"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+}
+
+// credentialResponse is the PublicKeyCredential a browser returns, with the
+// binary fields base64url encoded as in PublicKeyCredential.toJSON().
+type credentialResponse struct {
+	ID       string `json:"id"`
+	Response struct {
+		ClientDataJSON    string `json:"clientDataJSON"`
+		AttestationObject string `json:"attestationObject"`
+		AuthenticatorData string `json:"authenticatorData"`
+		Signature         string `json:"signature"`
+	} `json:"response"`
+}
+
+// challenge pulls the challenge the authenticator signed out of clientDataJSON
+// so the matching stored challenge can be looked up.
+func (c credentialResponse) challenge() ([]byte, []byte, error) {
+	clientDataJSON, err := base64.RawURLEncoding.DecodeString(c.Response.ClientDataJSON)
+	if err != nil {
+		return nil, nil, err
+	}
+	var cd struct {
+		Challenge string `json:"challenge"`
+	}
+	if err := json.Unmarshal(clientDataJSON, &cd); err != nil {
+		return nil, nil, err
+	}
+	challenge, err := base64.RawURLEncoding.DecodeString(cd.Challenge)
+	if err != nil {
+		return nil, nil, err
+	}
+	return challenge, clientDataJSON, nil
+}
+
+func (aCfg *apiConfig) handlePasskeyRegisterBegin(w http.ResponseWriter, r *http.Request) {
+	userID, _ := userIDFromContext(r.Context())
+
+	user, err := aCfg.db.GetUserById(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, 404, "user not found")
+		return
+	}
+	creds, err := aCfg.db.GetWebAuthnCredentialsByUser(r.Context(), user.ID)
+	if err != nil {
+		respondWithError(w, 500, "failed to get passkeys")
+		return
+	}
+
+	challenge, err := aCfg.newWebAuthnChallenge(r, uuid.NullUUID{Valid: true, UUID: user.ID}, ceremonyRegister)
+	if err != nil {
+		respondWithError(w, 500, "could not create challenge")
+		return
+	}
+
+	type credentialDescriptor struct {
+		Type string `json:"type"`
+		ID   string `json:"id"`
+	}
+	exclude := []credentialDescriptor{}
+	for _, c := range creds {
+		exclude = append(exclude, credentialDescriptor{Type: "public-key", ID: base64.RawURLEncoding.EncodeToString(c.CredentialID)})
+	}
+
+	respondWithJson(w, 200, map[string]interface{}{
+		"challenge": base64.RawURLEncoding.EncodeToString(challenge),
+		"rp":        map[string]string{"id": aCfg.webAuthn.RPID, "name": aCfg.webAuthn.RPName},
+		"user": map[string]string{
+			"id":          base64.RawURLEncoding.EncodeToString(user.ID[:]),
+			"name":        user.Email,
+			"displayName": user.Email,
+		},
+		"pubKeyCredParams":   []map[string]interface{}{{"type": "public-key", "alg": -7}},
+		"timeout":            webAuthnChallengeTTL.Milliseconds(),
+		"excludeCredentials": exclude,
+		"attestation":        "none",
+		"authenticatorSelection": map[string]string{
+			"residentKey":      "required",
+			"userVerification": "preferred",
+		},
+	})
+}
+
+func (aCfg *apiConfig) handlePasskeyRegisterFinish(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	type parameters struct {
+		Name       string             `json:"name"`
+		Credential credentialResponse `json:"credential"`
+	}
+
+	var params parameters
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+		respondWithError(w, 400, "invalid json format")
+		return
+	}
+
+	userID, _ := userIDFromContext(r.Context())
+
+	challenge, clientDataJSON, err := params.Credential.challenge()
+	if err != nil {
+		respondWithError(w, 400, "invalid client data")
+		return
+	}
+	stored, err := aCfg.consumeWebAuthnChallenge(r, challenge, ceremonyRegister)
+	if err != nil || stored.UserID.UUID != userID {
+		respondWithError(w, 400, "unknown or expired challenge")
+		return
+	}
+	attestationObject, err := base64.RawURLEncoding.DecodeString(params.Credential.Response.AttestationObject)
+	if err != nil {
+		respondWithError(w, 400, "invalid attestation object")
+		return
+	}
+
+	cred, err := aCfg.webAuthn.VerifyRegistration(challenge, clientDataJSON, attestationObject)
+	if err != nil {
+		respondWithError(w, 400, "passkey registration failed")
+		return
+	}
+
+	name := params.Name
+	if name == "" {
+		name = "Passkey"
+	}
+	row, err := aCfg.db.CreateWebAuthnCredential(r.Context(), database.CreateWebAuthnCredentialParams{
+		UserID:       userID,
+		Name:         name,
+		CredentialID: cred.ID,
+		PublicKey:    cred.PublicKey,
+		SignCount:    int64(cred.SignCount),
+	})
+	if err != nil {
+		respondWithError(w, 409, "passkey is already registered")
+		return
+	}
+
+	respondWithJson(w, 201, toPasskeyStruct(row))
+}
+
+func (aCfg *apiConfig) handlePasskeyLoginBegin(w http.ResponseWriter, r *http.Request) {
+	challenge, err := aCfg.newWebAuthnChallenge(r, uuid.NullUUID{}, ceremonyLogin)
+	if err != nil {
+		respondWithError(w, 500, "could not create challenge")
+		return
+	}
+
+	respondWithJson(w, 200, map[string]interface{}{
+		"challenge":        base64.RawURLEncoding.EncodeToString(challenge),
+		"rpId":             aCfg.webAuthn.RPID,
+		"timeout":          webAuthnChallengeTTL.Milliseconds(),
+		"userVerification": "preferred",
+	})
+}
+
+// handlePasskeyLoginFinish is the passkey counterpart to handleLogin. Passkeys
+// are a strong factor on their own, so no TOTP challenge follows.
+func (aCfg *apiConfig) handlePasskeyLoginFinish(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	var params credentialResponse
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+		respondWithError(w, 400, "invalid json format")
+		return
+	}
+
+	challenge, clientDataJSON, err := params.challenge()
+	if err != nil {
+		respondWithError(w, 400, "invalid client data")
+		return
+	}
+	if _, err := aCfg.consumeWebAuthnChallenge(r, challenge, ceremonyLogin); err != nil {
+		respondWithError(w, 401, "Unauthorized")
+		return
+	}
+
+	credID, err := base64.RawURLEncoding.DecodeString(params.ID)
+	if err != nil {
+		respondWithError(w, 401, "Unauthorized")
+		return
+	}
+	authData, err := base64.RawURLEncoding.DecodeString(params.Response.AuthenticatorData)
+	if err != nil {
+		respondWithError(w, 400, "invalid authenticator data")
+		return
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(params.Response.Signature)
+	if err != nil {
+		respondWithError(w, 400, "invalid signature")
+		return
+	}
+
+	row, err := aCfg.db.GetWebAuthnCredential(r.Context(), credID)
+	if err != nil {
+		respondWithError(w, 401, "Unauthorized")
+		return
+	}
+	signCount, err := aCfg.webAuthn.VerifyAssertion(challenge, auth.WebAuthnCredential{
+		ID:        row.CredentialID,
+		PublicKey: row.PublicKey,
+		SignCount: uint32(row.SignCount),
+	}, clientDataJSON, authData, signature)
+	if err != nil {
+		respondWithError(w, 401, "Unauthorized")
+		return
+	}
+	err = aCfg.db.UpdateWebAuthnSignCount(r.Context(), database.UpdateWebAuthnSignCountParams{
+		SignCount: int64(signCount),
+		ID:        row.ID,
+	})
+	if err != nil {
+		respondWithError(w, 500, "Failed to update database")
+		return
+	}
+
+	user, err := aCfg.db.GetUserById(r.Context(), row.UserID)
+	if err != nil {
+		respondWithError(w, 401, "Unauthorized")
+		return
+	}
+	aCfg.respondWithSession(w, r, user)
+}
+
+func (aCfg *apiConfig) handleListPasskeys(w http.ResponseWriter, r *http.Request) {
+	userID, _ := userIDFromContext(r.Context())
+
+	creds, err := aCfg.db.GetWebAuthnCredentialsByUser(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, 500, "failed to get passkeys")
+		return
+	}
+	resp := []passkeyStruct{}
+	for _, c := range creds {
+		resp = append(resp, toPasskeyStruct(c))
+	}
+	respondWithJson(w, 200, resp)
+}
+
+func (aCfg *apiConfig) handleDeletePasskey(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(r.PathValue("passkeyID"))
+	if err != nil {
+		respondWithError(w, 400, "invalid passkey id")
+		return
+	}
+	userID, _ := userIDFromContext(r.Context())
+
+	err = aCfg.db.DeleteWebAuthnCredential(r.Context(), database.DeleteWebAuthnCredentialParams{
+		ID:     id,
+		UserID: userID,
+	})
+	if err != nil {
+		respondWithError(w, 500, "failed to delete passkey")
+		return
+	}
+	respondWithJson(w, 204, nil)
+}
+
+func (aCfg *apiConfig) newWebAuthnChallenge(r *http.Request, userID uuid.NullUUID, ceremony string) ([]byte, error) {
+	challenge, err := auth.MakeWebAuthnChallenge()
+	if err != nil {
+		return nil, err
+	}
+	err = aCfg.db.CreateWebAuthnChallenge(r.Context(), database.CreateWebAuthnChallengeParams{
+		Challenge: challenge,
+		UserID:    userID,
+		Ceremony:  ceremony,
+		ExpiresAt: time.Now().UTC().Add(webAuthnChallengeTTL),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return challenge, nil
+}
+
+// consumeWebAuthnChallenge deletes the challenge as it reads it, so every
+// challenge can complete at most one ceremony.
+func (aCfg *apiConfig) consumeWebAuthnChallenge(r *http.Request, challenge []byte, ceremony string) (database.WebauthnChallenge, error) {
+	row, err := aCfg.db.ConsumeWebAuthnChallenge(r.Context(), database.ConsumeWebAuthnChallengeParams{
+		Challenge: challenge,
+		Ceremony:  ceremony,
+	})
+	if err != nil {
+		return row, err
+	}
+	if row.ExpiresAt.Before(time.Now().UTC()) {
+		return row, errWebAuthnChallengeExpired
+	}
+	return row, nil
+}
+
+func toPasskeyStruct(c database.WebauthnCredential) passkeyStruct {
+	resp := passkeyStruct{
+		ID:        c.ID,
+		CreatedAt: c.CreatedAt,
+		Name:      c.Name,
+	}
+	if c.LastUsedAt.Valid {
+		resp.LastUsedAt = &c.LastUsedAt.Time
+	}
+	return resp
+}
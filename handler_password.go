@@ -0,0 +1,133 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/anton-jj/chripy/internal/audit"
+	"github.com/anton-jj/chripy/internal/auth"
+	"github.com/anton-jj/chripy/internal/database"
+	"github.com/anton-jj/chripy/internal/mailer"
+)
+
+const passwordResetTTL = 30 * time.Minute
+
+// handleForgotPassword always answers the same way, and does its work in the
+// background, so neither the response nor its timing tells whether the email
+// belongs to an account.
+func (aCfg *apiConfig) handleForgotPassword(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	type parameters struct {
+		Email string `json:"email"`
+	}
+
+	var params parameters
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+		respondWithError(w, 400, "invalid json format")
+		return
+	}
+
+	go func(email string) {
+		if err := aCfg.sendPasswordReset(context.Background(), email); err != nil {
+			log.Printf("password reset: %v", err)
+		}
+	}(params.Email)
+
+	respondWithJson(w, 202, struct {
+		Message string `json:"message"`
+	}{Message: "If an account exists for that email, a reset link is on its way."})
+}
+
+func (aCfg *apiConfig) sendPasswordReset(ctx context.Context, email string) error {
+	user, err := aCfg.db.GetUserByEmail(ctx, email)
+	if err != nil {
+		return nil
+	}
+
+	token, err := auth.MakeRefreshToken()
+	if err != nil {
+		return err
+	}
+	err = aCfg.db.CreatePasswordResetToken(ctx, database.CreatePasswordResetTokenParams{
+		TokenHash: auth.HashToken(token),
+		UserID:    user.ID,
+		ExpiresAt: time.Now().UTC().Add(passwordResetTTL),
+	})
+	if err != nil {
+		return err
+	}
+
+	link := aCfg.baseURL + "/app/reset-password/?token=" + url.QueryEscape(token)
+	return aCfg.mailer.Send(ctx, mailer.Message{
+		To:      user.Email,
+		Subject: "Reset your Chirpy password",
+		Body: fmt.Sprintf("Someone asked to reset the password for your Chirpy account.\n\nOpen this link within %d minutes to choose a new one:\n\n%s\n\nIf it wasn't you, ignore this email; your password stays the same.\n",
+			int(passwordResetTTL.Minutes()), link),
+	})
+}
+
+func (aCfg *apiConfig) handleResetPassword(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	type parameters struct {
+		Token    string `json:"token"`
+		Password string `json:"password"`
+	}
+
+	var params parameters
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+		respondWithError(w, 400, "invalid json format")
+		return
+	}
+
+	// The token is only looked at here, and consumed once the new password
+	// has passed the policy, so a rejected password doesn't use up the link.
+	user, err := aCfg.db.GetUserByPasswordResetToken(r.Context(), auth.HashToken(params.Token))
+	if err != nil {
+		respondWithError(w, 400, "invalid or expired token")
+		return
+	}
+	if err := aCfg.passwordPolicy.Check(params.Password, user.Email); err != nil {
+		respondWithError(w, 400, err.Error())
+		return
+	}
+	reset, err := aCfg.db.ConsumePasswordResetToken(r.Context(), auth.HashToken(params.Token))
+	if err != nil {
+		respondWithError(w, 400, "invalid or expired token")
+		return
+	}
+
+	hashed, err := auth.HashPassword(params.Password)
+	if err != nil {
+		respondWithError(w, 500, "error hashing password")
+		return
+	}
+	err = aCfg.db.UpdateUserPassword(r.Context(), database.UpdateUserPasswordParams{
+		HashedPassword: hashed,
+		ID:             reset.UserID,
+	})
+	if err != nil {
+		respondWithError(w, 500, "Failed to update database")
+		return
+	}
+
+	// Whoever had the old password may still hold a session, an OAuth grant
+	// or an API key.
+	if err := aCfg.revokeAllTokens(r, reset.UserID); err != nil {
+		respondWithError(w, 500, "Failed to update database")
+		return
+	}
+	if err := aCfg.db.InvalidatePasswordResetTokens(r.Context(), reset.UserID); err != nil {
+		respondWithError(w, 500, "Failed to update database")
+		return
+	}
+	aCfg.recordAudit(r, audit.ActionPasswordReset, reset.UserID, reset.UserID, nil)
+
+	respondWithJson(w, 204, nil)
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"log"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/anton-jj/chripy/internal/database"
+	"github.com/anton-jj/chripy/internal/textlen"
+	"github.com/google/uuid"
+)
+
+const defaultReactions = "👍,❤️,😂,😮,😢,🎉"
+
+type reactionCount struct {
+	Emoji string `json:"emoji"`
+	Count int    `json:"count"`
+}
+
+// reactionSet is the emoji users may react with, in display order. Lookups
+// ignore variation selector 16, so "❤" and "❤️" are the same reaction.
+type reactionSet struct {
+	emoji []string
+	byKey map[string]string
+}
+
+func newReactionSet(list string) reactionSet {
+	set := reactionSet{byKey: map[string]string{}}
+	for _, e := range strings.Split(list, ",") {
+		e = textlen.Normalize(strings.TrimSpace(e))
+		if e == "" {
+			continue
+		}
+		if textlen.Graphemes(e) != 1 {
+			log.Printf("ignoring reaction %q: not a single emoji", e)
+			continue
+		}
+		if _, dup := set.byKey[reactionKey(e)]; dup {
+			continue
+		}
+		set.emoji = append(set.emoji, e)
+		set.byKey[reactionKey(e)] = e
+	}
+	return set
+}
+
+// reactionsFromEnv reads the allowed reactions from CHIRP_REACTIONS, a comma
+// separated list.
+func reactionsFromEnv() reactionSet {
+	list := os.Getenv("CHIRP_REACTIONS")
+	if list == "" {
+		list = defaultReactions
+	}
+	set := newReactionSet(list)
+	if len(set.emoji) == 0 {
+		log.Printf("CHIRP_REACTIONS has no usable emoji, using the defaults")
+		set = newReactionSet(defaultReactions)
+	}
+	return set
+}
+
+// canonical returns the configured spelling of e, if it is allowed.
+func (s reactionSet) canonical(e string) (string, bool) {
+	c, ok := s.byKey[reactionKey(textlen.Normalize(e))]
+	return c, ok
+}
+
+func reactionKey(e string) string {
+	return strings.ReplaceAll(e, "\uFE0F", "")
+}
+
+func (aCfg *apiConfig) handleAddReaction(w http.ResponseWriter, r *http.Request) {
+	userID, chirp, emoji, ok := aCfg.reactionTarget(w, r)
+	if !ok {
+		return
+	}
+	_, err := aCfg.db.AddReaction(r.Context(), database.AddReactionParams{
+		ChirpID: chirp.ID,
+		UserID:  userID,
+		Emoji:   emoji,
+	})
+	if err != nil {
+		respondWithError(w, 500, "Failed to update database")
+		return
+	}
+	respondWithJson(w, 204, nil)
+}
+
+func (aCfg *apiConfig) handleRemoveReaction(w http.ResponseWriter, r *http.Request) {
+	userID, chirp, emoji, ok := aCfg.reactionTarget(w, r)
+	if !ok {
+		return
+	}
+	_, err := aCfg.db.RemoveReaction(r.Context(), database.RemoveReactionParams{
+		ChirpID: chirp.ID,
+		UserID:  userID,
+		Emoji:   emoji,
+	})
+	if err != nil {
+		respondWithError(w, 500, "Failed to update database")
+		return
+	}
+	respondWithJson(w, 204, nil)
+}
+
+// reactionTarget resolves the chirp and emoji of a reaction request. Chirps
+// the caller can't see can't be reacted to.
+func (aCfg *apiConfig) reactionTarget(w http.ResponseWriter, r *http.Request) (uuid.UUID, database.Chirp, string, bool) {
+	userID, _ := userIDFromContext(r.Context())
+
+	id, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		respondWithError(w, 400, "invalid chirp id")
+		return uuid.Nil, database.Chirp{}, "", false
+	}
+	emoji, ok := aCfg.reactions.canonical(r.PathValue("emoji"))
+	if !ok {
+		respondWithError(w, 400, "reaction must be one of "+strings.Join(aCfg.reactions.emoji, " "))
+		return uuid.Nil, database.Chirp{}, "", false
+	}
+	chirp, err := aCfg.db.GetChirpById(r.Context(), id)
+	if err != nil || !aCfg.canViewChirp(r, chirp) {
+		respondWithError(w, 404, "chirp not found")
+		return uuid.Nil, database.Chirp{}, "", false
+	}
+	return userID, chirp, emoji, true
+}
+
+// attachReactions fills in the reaction totals of chirps, most used first,
+// and the signed in caller's own reactions. Totals come from
+// chirp_reaction_counts, which a trigger keeps in step with the reactions.
+func (aCfg *apiConfig) attachReactions(r *http.Request, chirps []Chirp) {
+	if len(chirps) == 0 {
+		return
+	}
+	ids := make([]uuid.UUID, len(chirps))
+	index := make(map[uuid.UUID]int, len(chirps))
+	for i := range chirps {
+		ids[i] = chirps[i].Id
+		index[chirps[i].Id] = i
+		chirps[i].Reactions = []reactionCount{}
+	}
+
+	counts, err := aCfg.db.GetReactionCounts(r.Context(), ids)
+	if err != nil {
+		log.Printf("failed to get reaction counts: %v", err)
+		return
+	}
+	for _, row := range counts {
+		c := &chirps[index[row.ChirpID]]
+		c.Reactions = append(c.Reactions, reactionCount{Emoji: row.Emoji, Count: int(row.Count)})
+	}
+
+	viewer, ok := userIDFromContext(r.Context())
+	if !ok {
+		return
+	}
+	mine, err := aCfg.db.GetViewerReactions(r.Context(), database.GetViewerReactionsParams{
+		UserID:   viewer,
+		ChirpIds: ids,
+	})
+	if err != nil {
+		log.Printf("failed to get reactions of %s: %v", viewer, err)
+		return
+	}
+	for _, row := range mine {
+		c := &chirps[index[row.ChirpID]]
+		c.MyReactions = append(c.MyReactions, row.Emoji)
+	}
+}
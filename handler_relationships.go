@@ -0,0 +1,138 @@
+package main
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/anton-jj/chripy/internal/database"
+	"github.com/google/uuid"
+)
+
+type relationshipStruct struct {
+	UserID    uuid.UUID `json:"user_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func (aCfg *apiConfig) handleBlockUser(w http.ResponseWriter, r *http.Request) {
+	userID, target, ok := aCfg.relationshipTarget(w, r)
+	if !ok {
+		return
+	}
+	err := aCfg.db.BlockUser(r.Context(), database.BlockUserParams{
+		BlockerID: userID,
+		BlockedID: target,
+	})
+	if err != nil {
+		respondWithError(w, 500, "Failed to update database")
+		return
+	}
+	respondWithJson(w, 204, nil)
+}
+
+func (aCfg *apiConfig) handleUnblockUser(w http.ResponseWriter, r *http.Request) {
+	userID, target, ok := aCfg.relationshipTarget(w, r)
+	if !ok {
+		return
+	}
+	err := aCfg.db.UnblockUser(r.Context(), database.UnblockUserParams{
+		BlockerID: userID,
+		BlockedID: target,
+	})
+	if err != nil {
+		respondWithError(w, 500, "Failed to update database")
+		return
+	}
+	respondWithJson(w, 204, nil)
+}
+
+func (aCfg *apiConfig) handleListBlocks(w http.ResponseWriter, r *http.Request) {
+	userID, _ := userIDFromContext(r.Context())
+
+	rows, err := aCfg.db.ListBlockedUsers(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, 500, "failed to get blocked users")
+		return
+	}
+	resp := []relationshipStruct{}
+	for _, row := range rows {
+		resp = append(resp, relationshipStruct{UserID: row.BlockedID, CreatedAt: row.CreatedAt})
+	}
+	respondWithJson(w, 200, resp)
+}
+
+func (aCfg *apiConfig) handleMuteUser(w http.ResponseWriter, r *http.Request) {
+	userID, target, ok := aCfg.relationshipTarget(w, r)
+	if !ok {
+		return
+	}
+	err := aCfg.db.MuteUser(r.Context(), database.MuteUserParams{
+		MuterID: userID,
+		MutedID: target,
+	})
+	if err != nil {
+		respondWithError(w, 500, "Failed to update database")
+		return
+	}
+	respondWithJson(w, 204, nil)
+}
+
+func (aCfg *apiConfig) handleUnmuteUser(w http.ResponseWriter, r *http.Request) {
+	userID, target, ok := aCfg.relationshipTarget(w, r)
+	if !ok {
+		return
+	}
+	err := aCfg.db.UnmuteUser(r.Context(), database.UnmuteUserParams{
+		MuterID: userID,
+		MutedID: target,
+	})
+	if err != nil {
+		respondWithError(w, 500, "Failed to update database")
+		return
+	}
+	respondWithJson(w, 204, nil)
+}
+
+func (aCfg *apiConfig) handleListMutes(w http.ResponseWriter, r *http.Request) {
+	userID, _ := userIDFromContext(r.Context())
+
+	rows, err := aCfg.db.ListMutedUsers(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, 500, "failed to get muted users")
+		return
+	}
+	resp := []relationshipStruct{}
+	for _, row := range rows {
+		resp = append(resp, relationshipStruct{UserID: row.MutedID, CreatedAt: row.CreatedAt})
+	}
+	respondWithJson(w, 200, resp)
+}
+
+// relationshipTarget reads the {userID} a block or mute is about. Users can't
+// block or mute themselves.
+func (aCfg *apiConfig) relationshipTarget(w http.ResponseWriter, r *http.Request) (uuid.UUID, uuid.UUID, bool) {
+	userID, _ := userIDFromContext(r.Context())
+	target, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		respondWithError(w, 400, "invalid user id")
+		return userID, target, false
+	}
+	if target == userID {
+		respondWithError(w, 400, "you cannot do that to yourself")
+		return userID, target, false
+	}
+	if _, err := aCfg.db.GetUserById(r.Context(), target); err != nil {
+		respondWithError(w, 404, "user not found")
+		return userID, target, false
+	}
+	return userID, target, true
+}
+
+// isBlocked reports whether either of the two users has blocked the other.
+// Errors count as blocked so a database hiccup doesn't leak content.
+func (aCfg *apiConfig) isBlocked(r *http.Request, a, b uuid.UUID) bool {
+	blocked, err := aCfg.db.IsBlockedEitherWay(r.Context(), database.IsBlockedEitherWayParams{
+		UserA: a,
+		UserB: b,
+	})
+	return err != nil || blocked
+}
@@ -0,0 +1,370 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/anton-jj/chripy/internal/audit"
+	"github.com/anton-jj/chripy/internal/database"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+const (
+	reportStatusOpen     = "open"
+	reportStatusResolved = "resolved"
+
+	reportKindUser  = "user"
+	reportKindChirp = "chirp"
+
+	// reportReasonAutomated marks reports filed by the moderation engine.
+	reportReasonAutomated = "automated"
+
+	resolutionDismiss       = "dismiss"
+	resolutionApprove       = "approve"
+	resolutionHideChirp     = "hide_chirp"
+	resolutionDeleteChirp   = "delete_chirp"
+	resolutionLimitAuthor   = "limit_author"
+	resolutionSuspendAuthor = "suspend_author"
+)
+
+// uniqueViolation is the Postgres error code for a unique constraint or
+// index being violated.
+const uniqueViolation = "23505"
+
+var reportReasons = []string{"spam", "harassment", "hate", "violence", "sexual", "self_harm", "impersonation", "other"}
+
+type reportStruct struct {
+	ID             uuid.UUID  `json:"id"`
+	CreatedAt      time.Time  `json:"created_at"`
+	ReporterID     *uuid.UUID `json:"reporter_id"`
+	ReportedUserID uuid.UUID  `json:"reported_user_id"`
+	Kind           string     `json:"kind"`
+	ChirpID        *uuid.UUID `json:"chirp_id"`
+	ChirpBody      string     `json:"chirp_body,omitempty"`
+	Reason         string     `json:"reason"`
+	Details        string     `json:"details"`
+	Status         string     `json:"status"`
+	Resolution     string     `json:"resolution,omitempty"`
+	ModeratorID    *uuid.UUID `json:"moderator_id,omitempty"`
+	ModeratorNotes string     `json:"moderator_notes,omitempty"`
+	ResolvedAt     *time.Time `json:"resolved_at,omitempty"`
+}
+
+func toReportStruct(r database.Report) reportStruct {
+	resp := reportStruct{
+		ID:             r.ID,
+		CreatedAt:      r.CreatedAt,
+		ReportedUserID: r.ReportedUserID,
+		Kind:           r.Kind,
+		ChirpBody:      r.ChirpBody,
+		Reason:         r.Reason,
+		Details:        r.Details,
+		Status:         r.Status,
+		Resolution:     r.Resolution,
+		ModeratorNotes: r.ModeratorNotes,
+	}
+	if r.ReporterID.Valid {
+		resp.ReporterID = &r.ReporterID.UUID
+	}
+	if r.ChirpID.Valid {
+		resp.ChirpID = &r.ChirpID.UUID
+	}
+	if r.ModeratorID.Valid {
+		resp.ModeratorID = &r.ModeratorID.UUID
+	}
+	if r.ResolvedAt.Valid {
+		resp.ResolvedAt = &r.ResolvedAt.Time
+	}
+	return resp
+}
+
+type reportParameters struct {
+	Reason  string `json:"reason"`
+	Details string `json:"details"`
+}
+
+func decodeReport(w http.ResponseWriter, r *http.Request) (reportParameters, bool) {
+	defer r.Body.Close()
+
+	var params reportParameters
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+		respondWithError(w, 400, "invalid json format")
+		return params, false
+	}
+	if !slices.Contains(reportReasons, params.Reason) {
+		respondWithError(w, 400, "reason must be one of spam, harassment, hate, violence, sexual, self_harm, impersonation or other")
+		return params, false
+	}
+	if len(params.Details) > 1000 {
+		respondWithError(w, 400, "details are too long")
+		return params, false
+	}
+	return params, true
+}
+
+func (aCfg *apiConfig) handleReportChirp(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		respondWithError(w, 400, "invalid chirp id")
+		return
+	}
+	params, ok := decodeReport(w, r)
+	if !ok {
+		return
+	}
+	userID, _ := userIDFromContext(r.Context())
+
+	chirp, err := aCfg.db.GetChirpById(r.Context(), id)
+	if err != nil || !aCfg.canViewChirp(r, chirp) {
+		respondWithError(w, 404, "chirp not found")
+		return
+	}
+	if chirp.UserID == userID {
+		respondWithError(w, 400, "you cannot report your own chirp")
+		return
+	}
+
+	aCfg.createReport(w, r, database.CreateReportParams{
+		ReporterID:     uuid.NullUUID{Valid: true, UUID: userID},
+		ReportedUserID: chirp.UserID,
+		ChirpID:        uuid.NullUUID{Valid: true, UUID: chirp.ID},
+		ChirpBody:      chirp.Body,
+		Reason:         params.Reason,
+		Details:        params.Details,
+		Kind:           reportKindChirp,
+	})
+}
+
+func (aCfg *apiConfig) handleReportUser(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		respondWithError(w, 400, "invalid user id")
+		return
+	}
+	params, ok := decodeReport(w, r)
+	if !ok {
+		return
+	}
+	userID, _ := userIDFromContext(r.Context())
+	if id == userID {
+		respondWithError(w, 400, "you cannot report yourself")
+		return
+	}
+	if _, err := aCfg.db.GetUserById(r.Context(), id); err != nil {
+		respondWithError(w, 404, "user not found")
+		return
+	}
+
+	aCfg.createReport(w, r, database.CreateReportParams{
+		ReporterID:     uuid.NullUUID{Valid: true, UUID: userID},
+		ReportedUserID: id,
+		Reason:         params.Reason,
+		Details:        params.Details,
+		Kind:           reportKindUser,
+	})
+}
+
+func (aCfg *apiConfig) createReport(w http.ResponseWriter, r *http.Request, params database.CreateReportParams) {
+	report, err := aCfg.db.CreateReport(r.Context(), params)
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == uniqueViolation {
+		respondWithError(w, 409, "you already have an open report for this")
+		return
+	}
+	if err != nil {
+		respondWithError(w, 500, "failed to create report")
+		return
+	}
+	respondWithJson(w, 201, struct {
+		ID     uuid.UUID `json:"id"`
+		Status string    `json:"status"`
+	}{ID: report.ID, Status: report.Status})
+}
+
+// handleAdminListReports is the moderation queue: open reports oldest first
+// by default, or any status with ?status=.
+func (aCfg *apiConfig) handleAdminListReports(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	status := query.Get("status")
+	if status == "" {
+		status = reportStatusOpen
+	}
+	if status == "all" {
+		status = ""
+	}
+	limit, err := queryInt(query.Get("limit"), adminPageSize)
+	if err != nil || limit < 1 || limit > adminMaxPageSize {
+		respondWithError(w, 400, "invalid limit")
+		return
+	}
+	offset, err := queryInt(query.Get("offset"), 0)
+	if err != nil || offset < 0 {
+		respondWithError(w, 400, "invalid offset")
+		return
+	}
+
+	reports, err := aCfg.db.ListReports(r.Context(), database.ListReportsParams{
+		Status:     status,
+		MaxResults: int32(limit),
+		Skip:       int32(offset),
+	})
+	if err != nil {
+		respondWithError(w, 500, "failed to get reports")
+		return
+	}
+	resp := []reportStruct{}
+	for _, rep := range reports {
+		resp = append(resp, toReportStruct(rep))
+	}
+	respondWithJson(w, 200, resp)
+}
+
+func (aCfg *apiConfig) handleAdminGetReport(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(r.PathValue("reportID"))
+	if err != nil {
+		respondWithError(w, 400, "invalid report id")
+		return
+	}
+	report, err := aCfg.db.GetReport(r.Context(), id)
+	if err != nil {
+		respondWithError(w, 404, "report not found")
+		return
+	}
+	respondWithJson(w, 200, toReportStruct(report))
+}
+
+// handleAdminResolveReport closes a report with one of the resolutions and
+// carries it out. Suspending the author needs permManageUsers, like the
+// suspend endpoint, and staff accounts can't be limited or suspended this
+// way at all. The report is claimed before anything is done, so two
+// moderators resolving it at once can't both act on it; if the action then
+// fails the report is opened again. The moderator's notes are kept on the
+// report and in the audit log.
+func (aCfg *apiConfig) handleAdminResolveReport(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	type parameters struct {
+		Action string `json:"action"`
+		Notes  string `json:"notes"`
+	}
+
+	id, err := uuid.Parse(r.PathValue("reportID"))
+	if err != nil {
+		respondWithError(w, 400, "invalid report id")
+		return
+	}
+	var params parameters
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+		respondWithError(w, 400, "invalid json format")
+		return
+	}
+
+	report, err := aCfg.db.GetReport(r.Context(), id)
+	if err != nil {
+		respondWithError(w, 404, "report not found")
+		return
+	}
+	if report.Status != reportStatusOpen {
+		respondWithError(w, 409, "report is already resolved")
+		return
+	}
+	moderatorID, _ := userIDFromContext(r.Context())
+
+	switch params.Action {
+	case resolutionDismiss:
+	case resolutionApprove, resolutionHideChirp, resolutionDeleteChirp:
+		if !report.ChirpID.Valid {
+			respondWithError(w, 400, "report is not about a chirp")
+			return
+		}
+	case resolutionLimitAuthor, resolutionSuspendAuthor:
+		if report.ReportedUserID == moderatorID {
+			respondWithError(w, 400, "you cannot moderate yourself")
+			return
+		}
+		// Suspending through a report must not be a way around the
+		// permission the suspend endpoint asks for.
+		if params.Action == resolutionSuspendAuthor {
+			moderator, err := aCfg.db.GetUserById(r.Context(), moderatorID)
+			if err != nil || !roleHas(moderator.Role, permManageUsers) {
+				respondWithError(w, 403, "Forbidden")
+				return
+			}
+		}
+		reported, err := aCfg.db.GetUserById(r.Context(), report.ReportedUserID)
+		if err != nil {
+			respondWithError(w, 404, "user not found")
+			return
+		}
+		if isStaff(reported.Role) {
+			respondWithError(w, 403, "staff accounts cannot be moderated through reports")
+			return
+		}
+	default:
+		respondWithError(w, 400, "action must be one of dismiss, approve, hide_chirp, delete_chirp, limit_author or suspend_author")
+		return
+	}
+
+	resolved, err := aCfg.db.ResolveReport(r.Context(), database.ResolveReportParams{
+		Resolution:     params.Action,
+		ModeratorID:    uuid.NullUUID{Valid: true, UUID: moderatorID},
+		ModeratorNotes: params.Notes,
+		ID:             report.ID,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, 409, "report is already resolved")
+		return
+	}
+	if err != nil {
+		respondWithError(w, 500, "Failed to update database")
+		return
+	}
+
+	if err := aCfg.applyResolution(r, report, params.Action); err != nil {
+		if err := aCfg.db.ReopenReport(r.Context(), report.ID); err != nil {
+			log.Printf("failed to reopen report %s: %v", report.ID, err)
+		}
+		respondWithError(w, 500, "Failed to update database")
+		return
+	}
+	aCfg.recordAdminAudit(r, audit.ActionReportResolved, report.ReportedUserID, map[string]interface{}{
+		"report_id":  report.ID,
+		"resolution": params.Action,
+		"notes":      params.Notes,
+	})
+	respondWithJson(w, 200, toReportStruct(resolved))
+}
+
+// applyResolution carries out action, already validated, on what report is
+// about.
+func (aCfg *apiConfig) applyResolution(r *http.Request, report database.Report, action string) error {
+	switch action {
+	case resolutionApprove:
+		return aCfg.db.UnhideChirp(r.Context(), report.ChirpID.UUID)
+	case resolutionHideChirp:
+		return aCfg.db.HideChirp(r.Context(), report.ChirpID.UUID)
+	case resolutionDeleteChirp:
+		return aCfg.db.DeleteChirpById(r.Context(), report.ChirpID.UUID)
+	case resolutionLimitAuthor:
+		return aCfg.db.LimitUser(r.Context(), database.LimitUserParams{
+			LimitReason: "report " + report.ID.String() + ": " + report.Reason,
+			ID:          report.ReportedUserID,
+		})
+	case resolutionSuspendAuthor:
+		err := aCfg.db.SuspendUser(r.Context(), database.SuspendUserParams{
+			SuspensionReason: "report " + report.ID.String() + ": " + report.Reason,
+			ID:               report.ReportedUserID,
+		})
+		if err != nil {
+			return err
+		}
+		return aCfg.revokeAllTokens(r, report.ReportedUserID)
+	}
+	return nil
+}
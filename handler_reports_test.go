@@ -0,0 +1,48 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/anton-jj/chripy/internal/database"
+	"github.com/google/uuid"
+)
+
+func TestResolveReportLimitsModerators(t *testing.T) {
+	aCfg := testAPIConfig(t)
+	moderator := createTestUser(t, aCfg, roleModerator)
+	admin := createTestUser(t, aCfg, roleAdmin)
+	reporter := createTestUser(t, aCfg, roleUser)
+	handler := aCfg.middlewareRequirePermission(permModerate, aCfg.handleAdminResolveReport)
+
+	tests := []struct {
+		name     string
+		caller   database.User
+		reported database.User
+		action   string
+		want     int
+	}{
+		{name: "moderator cannot suspend", caller: moderator, reported: createTestUser(t, aCfg, roleUser), action: resolutionSuspendAuthor, want: 403},
+		{name: "moderator cannot limit staff", caller: moderator, reported: admin, action: resolutionLimitAuthor, want: 403},
+		{name: "admin cannot suspend staff", caller: admin, reported: moderator, action: resolutionSuspendAuthor, want: 403},
+		{name: "moderator can limit a user", caller: moderator, reported: createTestUser(t, aCfg, roleUser), action: resolutionLimitAuthor, want: 200},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report, err := aCfg.db.CreateReport(context.Background(), database.CreateReportParams{
+				ReporterID:     uuid.NullUUID{Valid: true, UUID: reporter.ID},
+				ReportedUserID: tt.reported.ID,
+				Reason:         "spam",
+				Kind:           reportKindUser,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			rec := serve(t, aCfg, "POST /admin/reports/{reportID}/resolve", handler,
+				"POST", "/admin/reports/"+report.ID.String()+"/resolve", `{"action":"`+tt.action+`"}`, &tt.caller)
+			if rec.Code != tt.want {
+				t.Errorf("got %d, want %d: %s", rec.Code, tt.want, rec.Body)
+			}
+		})
+	}
+}
@@ -1,13 +1,15 @@
 package main
 
 import (
+	"context"
 	"database/sql"
 	"encoding/json"
 	"log"
 	"net/http"
-	"strings"
+	"net/mail"
 	"time"
 
+	"github.com/anton-jj/chripy/internal/audit"
 	"github.com/anton-jj/chripy/internal/auth"
 	"github.com/anton-jj/chripy/internal/database"
 	"github.com/google/uuid"
@@ -19,59 +21,155 @@ type parameters struct {
 }
 
 type userStruct struct {
-	ID           uuid.UUID `json:"id"`
-	CreatedAt    time.Time `json:"created_at"`
-	UpdatedAt    time.Time `json:"updated_at"`
-	Email        string    `json:"email"`
-	Token        string    `json:"token"`
-	RefreshToken string    `json:"refresh_token"`
+	ID               uuid.UUID `json:"id"`
+	CreatedAt        time.Time `json:"created_at"`
+	UpdatedAt        time.Time `json:"updated_at"`
+	Email            string    `json:"email"`
+	Token            string    `json:"token,omitempty"`
+	RefreshToken     string    `json:"refresh_token,omitempty"`
+	IsEmailVerified  bool      `json:"is_email_verified"`
+	PendingEmail     string    `json:"pending_email,omitempty"`
+	Role             string    `json:"role"`
+	SensitiveContent string    `json:"sensitive_content"`
 }
 
+func toUserStruct(user database.User) userStruct {
+	return userStruct{
+		ID:               user.ID,
+		CreatedAt:        user.CreatedAt,
+		UpdatedAt:        user.UpdatedAt,
+		Email:            user.Email,
+		IsEmailVerified:  user.EmailVerifiedAt.Valid,
+		PendingEmail:     user.PendingEmail.String,
+		Role:             user.Role,
+		SensitiveContent: user.SensitiveContent,
+	}
+}
+
+// handleUpdateUser applies a partial update to the signed in user. Both a new
+// password and a new email need the current password. A new email only takes
+// over once its owner follows the verification link, until then it is kept in
+// pending_email and the old address keeps working. The sensitive_content
+// preference can be changed on its own without a password.
 func (aCfg *apiConfig) handleUpdateUser(w http.ResponseWriter, r *http.Request) {
 	defer r.Body.Close()
 
-	var params parameters
+	type parameters struct {
+		Email            *string `json:"email"`
+		Password         *string `json:"password"`
+		CurrentPassword  string  `json:"current_password"`
+		SensitiveContent *string `json:"sensitive_content"`
+	}
 
+	var params parameters
 	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
 		respondWithError(w, 400, "invalid json format")
 		return
 	}
 
-	tok, err := auth.GetBearerToken(r.Header)
+	userID, _ := userIDFromContext(r.Context())
+	user, err := aCfg.db.GetUserById(r.Context(), userID)
 	if err != nil {
-		respondWithError(w, 401, "header missing or malformed")
-	}
-
-	if strings.Count(tok, ".") != 2 {
-		respondWithError(w, 401, "malformed token")
+		respondWithError(w, 404, "user not found")
 		return
 	}
 
-	_, err = auth.ValidateJWT(tok, aCfg.secret)
-	if err != nil {
-		respondWithError(w, 401, "Unauthorized")
+	if params.SensitiveContent != nil && !validSensitivePreference(*params.SensitiveContent) {
+		respondWithError(w, 400, "sensitive_content must be one of collapse, expand or hide")
 		return
 	}
+	if params.Email != nil && *params.Email == user.Email {
+		params.Email = nil
+	}
 
+	// Everything is checked before anything is written, so a request that
+	// fails the password check leaves the account exactly as it was.
+	if params.Email != nil || params.Password != nil {
+		if wait := aCfg.loginRetryAfter(r, user.Email); wait > 0 {
+			respondTooManyAttempts(w, wait)
+			return
+		}
+		match, err := auth.CheckPasswordHash(params.CurrentPassword, user.HashedPassword)
+		if err != nil || !match {
+			aCfg.loginFailed(r, user.Email)
+			respondWithError(w, 401, "current password is incorrect")
+			return
+		}
+	}
+	if params.Email != nil {
+		addr, err := mail.ParseAddress(*params.Email)
+		if err != nil || addr.Address != *params.Email {
+			respondWithError(w, 400, "invalid email address")
+			return
+		}
+		if _, err := aCfg.db.GetUserByEmail(r.Context(), *params.Email); err == nil {
+			respondWithError(w, 409, "email is already in use")
+			return
+		}
+	}
+	if params.Password != nil {
+		email := user.Email
+		if params.Email != nil {
+			email = *params.Email
+		}
+		if err := aCfg.passwordPolicy.Check(*params.Password, email); err != nil {
+			respondWithError(w, 400, err.Error())
+			return
+		}
+	}
 
-	hashedPass, err := auth.HashPassword(params.Password)
-	if err != nil {
-		respondWithError(w, 401, "Unauthorized")
-		return
+	if params.SensitiveContent != nil {
+		err = aCfg.db.SetSensitiveContentPreference(r.Context(), database.SetSensitiveContentPreferenceParams{
+			SensitiveContent: *params.SensitiveContent,
+			ID:               user.ID,
+		})
+		if err != nil {
+			respondWithError(w, 500, "Failed to update database")
+			return
+		}
 	}
-	updateUserParams := database.UpdateUserParams{
-		Email:          params.Email,
-		HashedPassword: hashedPass,
+	if params.Password != nil {
+		hashed, err := auth.HashPassword(*params.Password)
+		if err != nil {
+			respondWithError(w, 500, "error hashing password")
+			return
+		}
+		err = aCfg.db.UpdateUserPassword(r.Context(), database.UpdateUserPasswordParams{
+			HashedPassword: hashed,
+			ID:             user.ID,
+		})
+		if err != nil {
+			respondWithError(w, 500, "Failed to update database")
+			return
+		}
+		aCfg.recordAudit(r, audit.ActionPasswordChanged, user.ID, user.ID, nil)
+	}
+	if params.Email != nil {
+		err = aCfg.db.SetPendingEmail(r.Context(), database.SetPendingEmailParams{
+			PendingEmail: sql.NullString{Valid: true, String: *params.Email},
+			ID:           user.ID,
+		})
+		if err != nil {
+			respondWithError(w, 500, "Failed to update database")
+			return
+		}
+		aCfg.recordAudit(r, audit.ActionEmailChangeBegun, user.ID, user.ID, map[string]interface{}{
+			"old_email": user.Email,
+			"new_email": *params.Email,
+		})
+		if err := aCfg.sendEmailChange(r.Context(), user, *params.Email); err != nil {
+			log.Printf("failed to send email change messages for %s: %v", user.ID, err)
+		}
 	}
-	err = aCfg.db.UpdateUser(r.Context(), updateUserParams)
+
+	user, err = aCfg.db.GetUserById(r.Context(), userID)
 	if err != nil {
-		respondWithError(w, 500, "Failed to update database")
+		respondWithError(w, 500, "failed to get user")
 		return
 	}
-	
-	respondWithJson(w, 200, struct{Email string `json:"email"`}{Email: params.Email})
-
+	respondWithJson(w, 200, toUserStruct(user))
 }
+
 func (aCfg *apiConfig) handleLogin(w http.ResponseWriter, r *http.Request) {
 	defer r.Body.Close()
 
@@ -82,36 +180,103 @@ func (aCfg *apiConfig) handleLogin(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if params.Email == "" {
+		respondWithError(w, 400, "email cant be empty")
+		return
+	}
+
+	if wait := aCfg.loginRetryAfter(r, params.Email); wait > 0 {
+		respondTooManyAttempts(w, wait)
+		return
+	}
+
+	// Unknown emails and wrong passwords get the same answer, in about the same
+	// time, so the endpoint can't be used to find out who has an account.
 	user, err := aCfg.db.GetUserByEmail(r.Context(), params.Email)
+	hash := user.HashedPassword
 	if err != nil {
-		respondWithError(w, 400, "cant find user")
+		hash = dummyHash()
+	}
+	checked, hashErr := auth.CheckPasswordHash(params.Password, hash)
+	if err != nil || hashErr != nil || !checked {
+		aCfg.loginFailed(r, params.Email)
+		respondWithError(w, 401, "Incorrect email or password")
 		return
 	}
+	if user.SuspendedAt.Valid {
+		respondWithError(w, 403, "account suspended")
+		return
+	}
+	aCfg.upgradePasswordHash(user, params.Password)
 
-	if params.Email == "" {
-		respondWithError(w, 400, "email cant be empty")
+	if user.TotpEnabledAt.Valid {
+		mfaToken, err := auth.MakeMFAToken(user.ID, aCfg.secret, mfaChallengeTTL)
+		if err != nil {
+			respondWithError(w, 500, "Could not create a token")
+			return
+		}
+		respondWithJson(w, 200, mfaChallenge{MFARequired: true, MFAToken: mfaToken})
 		return
 	}
+	// With two-factor on, the counter is only cleared once the second step
+	// passes, so knowing the password doesn't reset the TOTP backoff.
+	aCfg.loginSucceeded(r, params.Email)
 
-	checked, err := auth.CheckPasswordHash(params.Password, user.HashedPassword)
-	if err != nil || !checked {
-		respondWithError(w, 401, "Incorrect email or password")
+	aCfg.respondWithSession(w, r, user)
+
+}
+
+// upgradePasswordHash re-hashes password with the current argon2id parameters
+// if user's stored hash was made with weaker ones. It runs in the background
+// and only logs failures; the old hash keeps working until it succeeds. The
+// new hash is only stored if the old one is still in place, so a password
+// change or forced reset that lands first is never undone.
+func (aCfg *apiConfig) upgradePasswordHash(user database.User, password string) {
+	needs, err := auth.NeedsRehash(user.HashedPassword)
+	if err != nil || !needs {
 		return
 	}
+	go func() {
+		hashed, err := auth.HashPassword(password)
+		if err != nil {
+			log.Printf("failed to rehash password for %s: %v", user.ID, err)
+			return
+		}
+		_, err = aCfg.db.RehashUserPassword(context.Background(), database.RehashUserPasswordParams{
+			NewHash: hashed,
+			ID:      user.ID,
+			OldHash: user.HashedPassword,
+		})
+		if err != nil {
+			log.Printf("failed to store rehashed password for %s: %v", user.ID, err)
+		}
+	}()
+}
 
+// respondWithSession finishes a login by handing out a fresh access token and
+// refresh token for user. It is the last step of every login flow, password,
+// TOTP and passkey alike, so it is where suspended accounts and accounts that
+// must reset their password are turned away.
+func (aCfg *apiConfig) respondWithSession(w http.ResponseWriter, r *http.Request, user database.User) {
+	if user.SuspendedAt.Valid {
+		respondWithError(w, 403, "account suspended")
+		return
+	}
+	if user.MustResetPassword {
+		respondWithError(w, 403, "password reset required, check your email for a reset link")
+		return
+	}
 	refreshToken, err := auth.MakeRefreshToken()
 	if err != nil {
 		respondWithError(w, 401, "Could not create a token")
 		return
 	}
-	log.Println(refreshToken)
 
 	token, err := auth.MakeJWT(user.ID, aCfg.secret, time.Hour)
 	if err != nil {
 		respondWithError(w, 401, "Could not create a token")
 		return
 	}
-	log.Println(token)
 
 	tokenParams := database.CreateRefreshTokenParams{
 		Token: sql.NullString{
@@ -128,17 +293,13 @@ func (aCfg *apiConfig) handleLogin(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	resp := userStruct{
-		ID:           user.ID,
-		CreatedAt:    user.CreatedAt,
-		UpdatedAt:    user.UpdatedAt,
-		Email:        user.Email,
-		Token:        token,
-		RefreshToken: refreshToken,
-	}
+	aCfg.recordAudit(r, audit.ActionLoginSucceeded, user.ID, user.ID, map[string]interface{}{"path": r.URL.Path})
 
-	respondWithJson(w, 200, resp)
+	resp := toUserStruct(user)
+	resp.Token = token
+	resp.RefreshToken = refreshToken
 
+	respondWithJson(w, 200, resp)
 }
 func (aCfg *apiConfig) handleUsers(w http.ResponseWriter, r *http.Request) {
 
@@ -153,6 +314,16 @@ func (aCfg *apiConfig) handleUsers(w http.ResponseWriter, r *http.Request) {
 
 	if params.Email == "" {
 		respondWithError(w, 400, "email cant be empty")
+		return
+	}
+	addr, err := mail.ParseAddress(params.Email)
+	if err != nil || addr.Address != params.Email {
+		respondWithError(w, 400, "invalid email address")
+		return
+	}
+	if err := aCfg.passwordPolicy.Check(params.Password, params.Email); err != nil {
+		respondWithError(w, 400, err.Error())
+		return
 	}
 	hashed, err := auth.HashPassword(params.Password)
 	if err != nil {
@@ -170,6 +341,10 @@ func (aCfg *apiConfig) handleUsers(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if err := aCfg.sendVerificationEmail(r.Context(), user); err != nil {
+		log.Printf("failed to send verification email to %s: %v", user.ID, err)
+	}
+
 	token, err := auth.MakeJWT(user.ID, aCfg.secret, time.Hour)
 	if err != nil {
 		respondWithError(w, 401, "Could not create a token")
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/anton-jj/chripy/internal/audit"
+	"github.com/anton-jj/chripy/internal/auth"
+	"github.com/anton-jj/chripy/internal/database"
+	"github.com/anton-jj/chripy/internal/mailer"
+)
+
+const emailTokenTTL = 24 * time.Hour
+
+func (aCfg *apiConfig) sendVerificationEmail(ctx context.Context, user database.User) error {
+	token, err := auth.MakeEmailToken(user.ID, user.Email, aCfg.secret, emailTokenTTL)
+	if err != nil {
+		return err
+	}
+	link := aCfg.baseURL + "/app/verify/?token=" + url.QueryEscape(token)
+	return aCfg.mailer.Send(ctx, mailer.Message{
+		To:      user.Email,
+		Subject: "Verify your Chirpy email address",
+		Body: fmt.Sprintf("Welcome to Chirpy!\n\nConfirm your email address by opening this link within %d hours:\n\n%s\n\nIf you did not sign up, you can ignore this email.\n",
+			int(emailTokenTTL.Hours()), link),
+	})
+}
+
+// sendEmailChange asks newEmail to confirm it belongs to user, and tells the
+// current address about the change so a hijacked session can't quietly move
+// the account.
+func (aCfg *apiConfig) sendEmailChange(ctx context.Context, user database.User, newEmail string) error {
+	token, err := auth.MakeEmailToken(user.ID, newEmail, aCfg.secret, emailTokenTTL)
+	if err != nil {
+		return err
+	}
+	link := aCfg.baseURL + "/app/verify/?token=" + url.QueryEscape(token)
+	err = aCfg.mailer.Send(ctx, mailer.Message{
+		To:      newEmail,
+		Subject: "Confirm your new Chirpy email address",
+		Body: fmt.Sprintf("Someone asked to move a Chirpy account to this address.\n\nConfirm the change by opening this link within %d hours:\n\n%s\n\nIf this was not you, you can ignore this email.\n",
+			int(emailTokenTTL.Hours()), link),
+	})
+	if err != nil {
+		return err
+	}
+	return aCfg.mailer.Send(ctx, mailer.Message{
+		To:      user.Email,
+		Subject: "Your Chirpy email address is being changed",
+		Body: fmt.Sprintf("A request was made to change the email address on your Chirpy account to %s.\n\nThe change takes effect once the new address is confirmed. If this was not you, reset your password right away.\n",
+			newEmail),
+	})
+}
+
+func (aCfg *apiConfig) handleVerifyEmail(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	type parameters struct {
+		Token string `json:"token"`
+	}
+
+	var params parameters
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+		respondWithError(w, 400, "invalid json format")
+		return
+	}
+
+	userID, email, err := auth.ValidateEmailToken(params.Token, aCfg.secret)
+	if err != nil {
+		respondWithError(w, 400, "invalid or expired token")
+		return
+	}
+	n, err := aCfg.db.VerifyUserEmail(r.Context(), database.VerifyUserEmailParams{
+		ID:    userID,
+		Email: email,
+	})
+	if err != nil {
+		respondWithError(w, 500, "Failed to update database")
+		return
+	}
+	if n == 0 {
+		// The token may be for an address the user is changing to.
+		n, err = aCfg.db.ConfirmEmailChange(r.Context(), database.ConfirmEmailChangeParams{
+			ID:           userID,
+			PendingEmail: sql.NullString{Valid: true, String: email},
+		})
+		if err != nil {
+			respondWithError(w, 409, "email is already in use")
+			return
+		}
+		if n > 0 {
+			aCfg.recordAudit(r, audit.ActionEmailChanged, userID, userID, map[string]interface{}{"new_email": email})
+		}
+	}
+	if n == 0 {
+		respondWithError(w, 400, "invalid or expired token")
+		return
+	}
+	respondWithJson(w, 204, nil)
+}
+
+func (aCfg *apiConfig) handleResendVerification(w http.ResponseWriter, r *http.Request) {
+	userID, _ := userIDFromContext(r.Context())
+
+	user, err := aCfg.db.GetUserById(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, 404, "user not found")
+		return
+	}
+	if user.EmailVerifiedAt.Valid {
+		respondWithError(w, 409, "email is already verified")
+		return
+	}
+	if err := aCfg.sendVerificationEmail(r.Context(), user); err != nil {
+		respondWithError(w, 500, "failed to send verification email")
+		return
+	}
+	respondWithJson(w, 204, nil)
+}
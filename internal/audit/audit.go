@@ -0,0 +1,137 @@
+// Package audit keeps an append-only record of security relevant events:
+// who did what to whom, from where.
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/anton-jj/chripy/internal/database"
+	"github.com/google/uuid"
+)
+
+const (
+	ActionLoginSucceeded    = "login.succeeded"
+	ActionLoginFailed       = "login.failed"
+	ActionTokenRefreshed    = "token.refreshed"
+	ActionTokenRevoked      = "token.revoked"
+	ActionPasswordChanged   = "user.password_changed"
+	ActionPasswordReset     = "user.password_reset"
+	ActionEmailChangeBegun  = "user.email_change_requested"
+	ActionEmailChanged      = "user.email_changed"
+	ActionUserDeleted       = "user.deleted"
+	ActionChirpDeleted      = "chirp.deleted"
+	ActionAdminChirpMarked  = "admin.chirp_marked"
+	ActionAdminSuspend      = "admin.user_suspended"
+	ActionAdminUnsuspend    = "admin.user_unsuspended"
+	ActionAdminLimit        = "admin.user_limited"
+	ActionAdminUnlimit      = "admin.user_unlimited"
+	ActionAdminForceReset   = "admin.password_reset_forced"
+	ActionAdminRevokeTokens = "admin.tokens_revoked"
+	ActionAdminReset        = "admin.database_reset"
+	ActionAdminRuleCreated  = "admin.moderation_rule_created"
+	ActionAdminRuleUpdated  = "admin.moderation_rule_updated"
+	ActionAdminRuleDeleted  = "admin.moderation_rule_deleted"
+	ActionReportResolved    = "moderation.report_resolved"
+)
+
+// Event is one entry in the log. ActorID is uuid.Nil when nobody is signed in,
+// for example for a failed login with an unknown email.
+type Event struct {
+	Time      time.Time
+	Action    string
+	ActorID   uuid.UUID
+	TargetID  uuid.UUID
+	IP        string
+	UserAgent string
+	Metadata  map[string]interface{}
+}
+
+// Store persists events. It must never modify or drop one once written.
+type Store interface {
+	Append(ctx context.Context, e Event) error
+}
+
+// Logger is the one way handlers write to the audit log.
+type Logger struct {
+	store Store
+}
+
+func New(store Store) *Logger {
+	return &Logger{store: store}
+}
+
+// Record writes e, stamping it with the current time if it has none. A failure
+// is logged but not returned: the action being audited has already happened.
+func (l *Logger) Record(ctx context.Context, e Event) {
+	if e.Time.IsZero() {
+		e.Time = time.Now().UTC()
+	}
+	if err := l.store.Append(ctx, e); err != nil {
+		log.Printf("audit: failed to record %s: %v", e.Action, err)
+	}
+}
+
+// MemoryStore keeps events in memory, for tests and single process setups.
+type MemoryStore struct {
+	mu     sync.Mutex
+	events []Event
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{}
+}
+
+func (s *MemoryStore) Append(ctx context.Context, e Event) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.events = append(s.events, e)
+	return nil
+}
+
+// Events returns a copy of everything recorded so far.
+func (s *MemoryStore) Events() []Event {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Event(nil), s.events...)
+}
+
+// PostgresStore writes to the audit_events table.
+type PostgresStore struct {
+	db *database.Queries
+}
+
+func NewPostgresStore(db *database.Queries) *PostgresStore {
+	return &PostgresStore{db: db}
+}
+
+func (s *PostgresStore) Append(ctx context.Context, e Event) error {
+	metadata, err := MarshalMetadata(e.Metadata)
+	if err != nil {
+		return err
+	}
+	return s.db.CreateAuditEvent(ctx, database.CreateAuditEventParams{
+		CreatedAt: e.Time,
+		Action:    e.Action,
+		ActorID:   nullUUID(e.ActorID),
+		TargetID:  nullUUID(e.TargetID),
+		Ip:        e.IP,
+		UserAgent: e.UserAgent,
+		Metadata:  metadata,
+	})
+}
+
+// MarshalMetadata encodes metadata for storage; nil becomes an empty object.
+func MarshalMetadata(metadata map[string]interface{}) (json.RawMessage, error) {
+	if metadata == nil {
+		return json.RawMessage("{}"), nil
+	}
+	return json.Marshal(metadata)
+}
+
+func nullUUID(id uuid.UUID) uuid.NullUUID {
+	return uuid.NullUUID{UUID: id, Valid: id != uuid.Nil}
+}
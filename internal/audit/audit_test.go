@@ -0,0 +1,64 @@
+package audit
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+type failingStore struct{}
+
+func (failingStore) Append(ctx context.Context, e Event) error {
+	return errors.New("disk full")
+}
+
+func TestLoggerRecord(t *testing.T) {
+	store := NewMemoryStore()
+	logger := New(store)
+
+	actor := uuid.New()
+	logger.Record(context.Background(), Event{
+		Action:   ActionLoginSucceeded,
+		ActorID:  actor,
+		TargetID: actor,
+		IP:       "203.0.113.7",
+		Metadata: map[string]interface{}{"method": "password"},
+	})
+
+	events := store.Events()
+	if len(events) != 1 {
+		t.Fatalf("expected 1 event, got %d", len(events))
+	}
+	if events[0].Time.IsZero() {
+		t.Errorf("expected Record to stamp the event time")
+	}
+	if events[0].ActorID != actor || events[0].Action != ActionLoginSucceeded {
+		t.Errorf("unexpected event %+v", events[0])
+	}
+
+	// A broken store must not take the request down with it.
+	New(failingStore{}).Record(context.Background(), Event{Action: ActionLoginFailed})
+}
+
+func TestMarshalMetadata(t *testing.T) {
+	got, err := MarshalMetadata(nil)
+	if err != nil || string(got) != "{}" {
+		t.Errorf("MarshalMetadata(nil) = %s, %v", got, err)
+	}
+	got, err = MarshalMetadata(map[string]interface{}{"reason": "spam"})
+	if err != nil || string(got) != `{"reason":"spam"}` {
+		t.Errorf("MarshalMetadata() = %s, %v", got, err)
+	}
+}
+
+func TestNullUUID(t *testing.T) {
+	if nullUUID(uuid.Nil).Valid {
+		t.Errorf("expected uuid.Nil to be stored as NULL")
+	}
+	id := uuid.New()
+	if got := nullUUID(id); !got.Valid || got.UUID != id {
+		t.Errorf("nullUUID(%s) = %+v", id, got)
+	}
+}
@@ -1,12 +1,15 @@
 package auth
 
 import (
+	"crypto/sha256"
 	"encoding/hex"
 	"fmt"
 	"strings"
 	"time"
 
 	"net/http"
+	"runtime"
+	"sync/atomic"
 
 	"crypto/rand"
 
@@ -15,6 +18,22 @@ import (
 	"github.com/google/uuid"
 )
 
+const (
+	ScopeChirpsRead   = "chirps:read"
+	ScopeChirpsWrite  = "chirps:write"
+	ScopeProfileWrite = "profile:write"
+)
+
+const apiKeyPrefix = "chirpy_"
+
+func ValidScope(scope string) bool {
+	switch scope {
+	case ScopeChirpsRead, ScopeChirpsWrite, ScopeProfileWrite:
+		return true
+	}
+	return false
+}
+
 func MakeRefreshToken() (string, error) {
 	buf := make([]byte, 32)
 	_, err := rand.Read(buf)
@@ -47,7 +66,7 @@ func ValidateJWT(tokenString, tokenSecret string) (uuid.UUID, error) {
 			return nil, fmt.Errorf("unexpected signing method")
 		}
 		return []byte(tokenSecret), nil
-	})
+	}, jwt.WithIssuer("chirpy"))
 	if err != nil {
 		return uuid.Nil, err
 	}
@@ -63,9 +82,49 @@ func ValidateJWT(tokenString, tokenSecret string) (uuid.UUID, error) {
 	return userID, nil
 }
 
+// HashConfig controls password hashing. Params are used for new hashes;
+// MaxConcurrent bounds how many hashes run at once so a burst of logins queues
+// up instead of pinning every core.
+type HashConfig struct {
+	Params        *argon2id.Params
+	MaxConcurrent int
+}
+
+type hasher struct {
+	params *argon2id.Params
+	sem    chan struct{}
+}
+
+var currentHasher atomic.Pointer[hasher]
+
+func init() {
+	ConfigureHashing(HashConfig{Params: argon2id.DefaultParams, MaxConcurrent: runtime.NumCPU()})
+}
+
+// ConfigureHashing replaces the hashing setup. It is meant to be called once at
+// startup.
+func ConfigureHashing(cfg HashConfig) {
+	params := cfg.Params
+	if params == nil {
+		params = argon2id.DefaultParams
+	}
+	n := cfg.MaxConcurrent
+	if n <= 0 {
+		n = runtime.NumCPU()
+	}
+	currentHasher.Store(&hasher{params: params, sem: make(chan struct{}, n)})
+}
+
+func (h *hasher) acquire() func() {
+	h.sem <- struct{}{}
+	return func() { <-h.sem }
+}
+
 func HashPassword(password string) (string, error) {
+	h := currentHasher.Load()
+	defer h.acquire()()
 
-	hash, err := argon2id.CreateHash(password, argon2id.DefaultParams)
+	hash, err := argon2id.CreateHash(password, h.params)
 	if err != nil {
 		return "", err
 	}
@@ -73,6 +132,9 @@ func HashPassword(password string) (string, error) {
 }
 
 func CheckPasswordHash(password, hash string) (bool, error) {
+	h := currentHasher.Load()
+	defer h.acquire()()
+
 	match, err := argon2id.ComparePasswordAndHash(password, hash)
 	if err != nil {
 		return false, err
@@ -81,6 +143,21 @@ func CheckPasswordHash(password, hash string) (bool, error) {
 	return match, nil
 }
 
+// NeedsRehash reports whether hash was made with weaker parameters than the
+// ones currently configured, so it should be replaced on the next login.
+func NeedsRehash(hash string) (bool, error) {
+	params, _, _, err := argon2id.DecodeHash(hash)
+	if err != nil {
+		return false, err
+	}
+	want := currentHasher.Load().params
+	return params.Memory < want.Memory ||
+		params.Iterations < want.Iterations ||
+		params.Parallelism < want.Parallelism ||
+		params.SaltLength < want.SaltLength ||
+		params.KeyLength < want.KeyLength, nil
+}
+
 func GetBearerToken(headers http.Header) (string, error) {
 	auth := headers.Get("Authorization")
 	if auth == "" {
@@ -93,3 +170,35 @@ func GetBearerToken(headers http.Header) (string, error) {
  
 	return parts[1], nil
 }
+
+// MakeAPIKey returns a new random API key. Only its hash is meant to be
+// stored, so the caller has to hand the key to the user right away.
+func MakeAPIKey() (string, error) {
+	buf := make([]byte, 32)
+	_, err := rand.Read(buf)
+	if err != nil {
+		return "", err
+	}
+	return apiKeyPrefix + hex.EncodeToString(buf), nil
+}
+
+// HashToken is a plain SHA-256 for random secrets such as API keys, OAuth
+// client secrets and codes; they carry 256 bits of entropy so a slow password
+// hash would only cost us on every request.
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func GetAPIKey(headers http.Header) (string, error) {
+	auth := headers.Get("Authorization")
+	if auth == "" {
+		return "", fmt.Errorf("no api key")
+	}
+	parts := strings.Fields(auth)
+	if len(parts) != 2 || !strings.EqualFold(parts[0], "ApiKey") || !strings.HasPrefix(parts[1], apiKeyPrefix) {
+		return "", fmt.Errorf("invalid header format")
+	}
+
+	return parts[1], nil
+}
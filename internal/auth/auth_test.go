@@ -5,6 +5,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/alexedwards/argon2id"
 	"github.com/google/uuid"
 )
 
@@ -71,6 +72,37 @@ func TestCheckPasswordHash(t *testing.T) {
 	}
 }
 
+func TestNeedsRehash(t *testing.T) {
+	weak := &argon2id.Params{Memory: 8 * 1024, Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 32}
+	strong := &argon2id.Params{Memory: 16 * 1024, Iterations: 2, Parallelism: 1, SaltLength: 16, KeyLength: 32}
+	t.Cleanup(func() { ConfigureHashing(HashConfig{}) })
+
+	ConfigureHashing(HashConfig{Params: weak, MaxConcurrent: 1})
+	weakHash, err := HashPassword("password")
+	if err != nil {
+		t.Fatalf("HashPassword failed %v", err)
+	}
+
+	ConfigureHashing(HashConfig{Params: strong, MaxConcurrent: 1})
+	strongHash, err := HashPassword("password")
+	if err != nil {
+		t.Fatalf("HashPassword failed %v", err)
+	}
+
+	if needs, err := NeedsRehash(weakHash); err != nil || !needs {
+		t.Errorf("expected weak hash to need a rehash, got %v %v", needs, err)
+	}
+	if needs, err := NeedsRehash(strongHash); err != nil || needs {
+		t.Errorf("expected strong hash to be kept, got %v %v", needs, err)
+	}
+	if match, err := CheckPasswordHash("password", weakHash); err != nil || !match {
+		t.Errorf("expected weak hash to still verify, got %v %v", match, err)
+	}
+	if _, err := NeedsRehash("invalidhash"); err == nil {
+		t.Errorf("expected an error for an invalid hash")
+	}
+}
+
 func TestValidateJWT(t *testing.T) {
 	userID := uuid.New()
 	secret := "someSecret"
@@ -155,3 +187,50 @@ func TestGetBearerToken(t *testing.T) {
 		t.Errorf("expected %q, got %q", stipedToken, token)
 	}
 }
+
+func TestGetAPIKey(t *testing.T) {
+	key, err := MakeAPIKey()
+	if err != nil {
+		t.Fatalf("MakeAPIKey() had an error %v", err)
+	}
+	tests := []struct {
+		name    string
+		header  string
+		wantErr bool
+	}{
+		{
+			name:    "valid key",
+			header:  "ApiKey " + key,
+			wantErr: false,
+		},
+		{
+			name:    "bearer scheme",
+			header:  "Bearer " + key,
+			wantErr: true,
+		},
+		{
+			name:    "missing prefix",
+			header:  "ApiKey abc123",
+			wantErr: true,
+		},
+		{
+			name:    "empty header",
+			header:  "",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			headers := http.Header{}
+			headers.Set("Authorization", tt.header)
+			got, err := GetAPIKey(headers)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("GetAPIKey() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && got != key {
+				t.Errorf("expected %q, got %q", key, got)
+			}
+		})
+	}
+}
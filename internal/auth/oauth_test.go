@@ -0,0 +1,86 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestVerifyPKCE(t *testing.T) {
+	verifier := strings.Repeat("a", 43)
+	sum := sha256.Sum256([]byte(verifier))
+	challenge := base64.RawURLEncoding.EncodeToString(sum[:])
+
+	tests := []struct {
+		name      string
+		verifier  string
+		challenge string
+		want      bool
+	}{
+		{
+			name:      "matching verifier",
+			verifier:  verifier,
+			challenge: challenge,
+			want:      true,
+		},
+		{
+			name:      "wrong verifier",
+			verifier:  strings.Repeat("b", 43),
+			challenge: challenge,
+			want:      false,
+		},
+		{
+			name:      "verifier too short",
+			verifier:  "short",
+			challenge: challenge,
+			want:      false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := VerifyPKCE(tt.verifier, tt.challenge); got != tt.want {
+				t.Errorf("VerifyPKCE() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateAccessToken(t *testing.T) {
+	tokenID := uuid.New()
+	userID := uuid.New()
+	clientID := uuid.New()
+	secret := "someSecret"
+
+	token, err := MakeAccessToken(tokenID, userID, clientID, []string{ScopeChirpsRead}, secret, time.Minute)
+	if err != nil {
+		t.Fatalf("MakeAccessToken failed %v", err)
+	}
+
+	claims, err := ValidateAccessToken(token, secret)
+	if err != nil {
+		t.Fatalf("ValidateAccessToken() had an error %v", err)
+	}
+	if claims.ID != tokenID.String() || claims.Subject != userID.String() || claims.ClientID != clientID.String() {
+		t.Errorf("unexpected claims %+v", claims)
+	}
+	if scopes := claims.Scopes(); len(scopes) != 1 || scopes[0] != ScopeChirpsRead {
+		t.Errorf("expected scopes [%s], got %v", ScopeChirpsRead, scopes)
+	}
+
+	if _, err := ValidateJWT(token, secret); err == nil {
+		t.Errorf("ValidateJWT() accepted an oauth access token")
+	}
+
+	session, err := MakeJWT(userID, secret, time.Minute)
+	if err != nil {
+		t.Fatalf("MakeJWT failed %v", err)
+	}
+	if _, err := ValidateAccessToken(session, secret); err == nil {
+		t.Errorf("ValidateAccessToken() accepted a session token")
+	}
+}
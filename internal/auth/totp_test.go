@@ -0,0 +1,96 @@
+package auth
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestTOTPCode(t *testing.T) {
+	// Test vectors from RFC 6238 appendix B (SHA1).
+	key := []byte("12345678901234567890")
+	tests := []struct {
+		unix int64
+		want string
+	}{
+		{unix: 59, want: "94287082"},
+		{unix: 1111111109, want: "07081804"},
+		{unix: 1234567890, want: "89005924"},
+		{unix: 2000000000, want: "69279037"},
+	}
+
+	for _, tt := range tests {
+		if got := totpCode(key, tt.unix/totpPeriod, 8); got != tt.want {
+			t.Errorf("totpCode(%d) = %s, want %s", tt.unix, got, tt.want)
+		}
+	}
+}
+
+func TestValidateTOTP(t *testing.T) {
+	secret, err := MakeTOTPSecret()
+	if err != nil {
+		t.Fatalf("MakeTOTPSecret failed %v", err)
+	}
+	key, _ := totpEncoding.DecodeString(secret)
+	now := time.Unix(1700000000, 0)
+	step := now.Unix() / totpPeriod
+
+	tests := []struct {
+		name     string
+		code     string
+		wantOK   bool
+		wantStep int64
+	}{
+		{
+			name:     "current step",
+			code:     totpCode(key, step, totpDigits),
+			wantOK:   true,
+			wantStep: step,
+		},
+		{
+			name:     "previous step within drift",
+			code:     totpCode(key, step-1, totpDigits),
+			wantOK:   true,
+			wantStep: step - 1,
+		},
+		{
+			name:   "too old",
+			code:   totpCode(key, step-2, totpDigits),
+			wantOK: false,
+		},
+		{
+			name:   "wrong length",
+			code:   "123",
+			wantOK: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotStep, ok := ValidateTOTP(tt.code, secret, now)
+			if ok != tt.wantOK {
+				t.Errorf("ValidateTOTP() ok = %v, want %v", ok, tt.wantOK)
+			}
+			if tt.wantOK && gotStep != tt.wantStep {
+				t.Errorf("ValidateTOTP() step = %d, want %d", gotStep, tt.wantStep)
+			}
+		})
+	}
+}
+
+func TestValidateMFAToken(t *testing.T) {
+	userID := uuid.New()
+	secret := "someSecret"
+	token, err := MakeMFAToken(userID, secret, time.Minute)
+	if err != nil {
+		t.Fatalf("MakeMFAToken failed %v", err)
+	}
+	got, err := ValidateMFAToken(token, secret)
+	if err != nil || got != userID {
+		t.Errorf("ValidateMFAToken() = %v, %v", got, err)
+	}
+	if _, err := ValidateJWT(token, secret); err == nil {
+		t.Errorf("ValidateJWT() accepted an mfa challenge token")
+	}
+}
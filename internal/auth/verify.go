@@ -0,0 +1,54 @@
+package auth
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+const verifyIssuer = "chirpy-verify"
+
+type emailClaims struct {
+	Email string `json:"email"`
+	jwt.RegisteredClaims
+}
+
+// MakeEmailToken signs the link sent to prove ownership of email. The address
+// is part of the token, so a link stops working once the user changes it.
+func MakeEmailToken(userID uuid.UUID, email, tokenSecret string, expiresIn time.Duration) (string, error) {
+	currentTime := time.Now().UTC()
+	claims := emailClaims{
+		Email: email,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    verifyIssuer,
+			IssuedAt:  jwt.NewNumericDate(currentTime),
+			ExpiresAt: jwt.NewNumericDate(currentTime.Add(expiresIn)),
+			Subject:   userID.String(),
+		},
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return token.SignedString([]byte(tokenSecret))
+}
+
+func ValidateEmailToken(tokenString, tokenSecret string) (uuid.UUID, string, error) {
+	token, err := jwt.ParseWithClaims(tokenString, &emailClaims{}, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method")
+		}
+		return []byte(tokenSecret), nil
+	}, jwt.WithIssuer(verifyIssuer))
+	if err != nil {
+		return uuid.Nil, "", err
+	}
+	claims, ok := token.Claims.(*emailClaims)
+	if !ok || !token.Valid {
+		return uuid.Nil, "", jwt.ErrTokenInvalidClaims
+	}
+	userID, err := uuid.Parse(claims.Subject)
+	if err != nil {
+		return uuid.Nil, "", err
+	}
+	return userID, claims.Email, nil
+}
@@ -0,0 +1,53 @@
+package auth
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestValidateEmailToken(t *testing.T) {
+	userID := uuid.New()
+	secret := "someSecret"
+	email := "user@example.com"
+
+	validToken, err := MakeEmailToken(userID, email, secret, time.Hour)
+	if err != nil {
+		t.Fatalf("MakeEmailToken failed %v", err)
+	}
+	expiredToken, err := MakeEmailToken(userID, email, secret, -time.Second)
+	if err != nil {
+		t.Fatalf("MakeEmailToken failed %v", err)
+	}
+	sessionToken, err := MakeJWT(userID, secret, time.Hour)
+	if err != nil {
+		t.Fatalf("MakeJWT failed %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		token   string
+		wantErr bool
+	}{
+		{name: "valid token", token: validToken, wantErr: false},
+		{name: "expired token", token: expiredToken, wantErr: true},
+		{name: "session token", token: sessionToken, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotID, gotEmail, err := ValidateEmailToken(tt.token, secret)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ValidateEmailToken() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && (gotID != userID || gotEmail != email) {
+				t.Errorf("expected %v %q, got %v %q", userID, email, gotID, gotEmail)
+			}
+		})
+	}
+
+	if _, err := ValidateJWT(validToken, secret); err == nil {
+		t.Errorf("ValidateJWT() accepted an email token")
+	}
+}
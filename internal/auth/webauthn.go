@@ -0,0 +1,218 @@
+package auth
+
+import (
+	"bytes"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+const (
+	flagUserPresent  = 0x01
+	flagAttestedData = 0x40
+
+	coseAlgES256 = -7
+)
+
+var (
+	ErrWebAuthnClientData = errors.New("webauthn: client data does not match the ceremony")
+	ErrWebAuthnAuthData   = errors.New("webauthn: invalid authenticator data")
+	ErrWebAuthnSignature  = errors.New("webauthn: invalid signature")
+	ErrWebAuthnSignCount  = errors.New("webauthn: sign counter did not increase, authenticator may be cloned")
+)
+
+// WebAuthn verifies passkey registration and assertion ceremonies for a single
+// relying party. Only "none" attestation and ES256 credentials are supported,
+// which is what platform authenticators and password managers hand out.
+type WebAuthn struct {
+	RPID   string
+	RPName string
+	Origin string
+}
+
+// WebAuthnCredential is what gets stored per registered passkey.
+type WebAuthnCredential struct {
+	ID        []byte
+	PublicKey []byte
+	SignCount uint32
+}
+
+type clientData struct {
+	Type      string `json:"type"`
+	Challenge string `json:"challenge"`
+	Origin    string `json:"origin"`
+}
+
+type authenticatorData struct {
+	RPIDHash     []byte
+	Flags        byte
+	SignCount    uint32
+	CredentialID []byte
+	PublicKey    []byte
+}
+
+func MakeWebAuthnChallenge() ([]byte, error) {
+	buf := make([]byte, 32)
+	_, err := rand.Read(buf)
+	if err != nil {
+		return nil, err
+	}
+	return buf, nil
+}
+
+// VerifyRegistration checks the response to navigator.credentials.create and
+// returns the new credential.
+func (wa WebAuthn) VerifyRegistration(challenge, clientDataJSON, attestationObject []byte) (WebAuthnCredential, error) {
+	if err := wa.verifyClientData(clientDataJSON, "webauthn.create", challenge); err != nil {
+		return WebAuthnCredential{}, err
+	}
+
+	obj, _, err := decodeCBOR(attestationObject)
+	if err != nil {
+		return WebAuthnCredential{}, err
+	}
+	att, ok := obj.(map[interface{}]interface{})
+	if !ok {
+		return WebAuthnCredential{}, errCBOR
+	}
+	if format, _ := att["fmt"].(string); format != "none" {
+		return WebAuthnCredential{}, fmt.Errorf("webauthn: unsupported attestation format %q", format)
+	}
+	rawAuthData, ok := att["authData"].([]byte)
+	if !ok {
+		return WebAuthnCredential{}, ErrWebAuthnAuthData
+	}
+
+	ad, err := wa.parseAuthenticatorData(rawAuthData)
+	if err != nil {
+		return WebAuthnCredential{}, err
+	}
+	if ad.Flags&flagAttestedData == 0 || len(ad.CredentialID) == 0 {
+		return WebAuthnCredential{}, ErrWebAuthnAuthData
+	}
+	if _, err := parseCOSEKey(ad.PublicKey); err != nil {
+		return WebAuthnCredential{}, err
+	}
+
+	return WebAuthnCredential{
+		ID:        ad.CredentialID,
+		PublicKey: ad.PublicKey,
+		SignCount: ad.SignCount,
+	}, nil
+}
+
+// VerifyAssertion checks the response to navigator.credentials.get against a
+// stored credential and returns the authenticator's new sign counter.
+func (wa WebAuthn) VerifyAssertion(challenge []byte, cred WebAuthnCredential, clientDataJSON, rawAuthData, signature []byte) (uint32, error) {
+	if err := wa.verifyClientData(clientDataJSON, "webauthn.get", challenge); err != nil {
+		return 0, err
+	}
+	ad, err := wa.parseAuthenticatorData(rawAuthData)
+	if err != nil {
+		return 0, err
+	}
+
+	pub, err := parseCOSEKey(cred.PublicKey)
+	if err != nil {
+		return 0, err
+	}
+	clientDataHash := sha256.Sum256(clientDataJSON)
+	digest := sha256.Sum256(append(append([]byte{}, rawAuthData...), clientDataHash[:]...))
+	if !ecdsa.VerifyASN1(pub, digest[:], signature) {
+		return 0, ErrWebAuthnSignature
+	}
+
+	// Authenticators without a counter always report zero.
+	if (ad.SignCount != 0 || cred.SignCount != 0) && ad.SignCount <= cred.SignCount {
+		return 0, ErrWebAuthnSignCount
+	}
+	return ad.SignCount, nil
+}
+
+func (wa WebAuthn) verifyClientData(raw []byte, ceremony string, challenge []byte) error {
+	var cd clientData
+	if err := json.Unmarshal(raw, &cd); err != nil {
+		return err
+	}
+	got, err := base64.RawURLEncoding.DecodeString(cd.Challenge)
+	if err != nil {
+		return ErrWebAuthnClientData
+	}
+	if cd.Type != ceremony || cd.Origin != wa.Origin || !bytes.Equal(got, challenge) {
+		return ErrWebAuthnClientData
+	}
+	return nil
+}
+
+func (wa WebAuthn) parseAuthenticatorData(data []byte) (authenticatorData, error) {
+	var ad authenticatorData
+	if len(data) < 37 {
+		return ad, ErrWebAuthnAuthData
+	}
+	ad.RPIDHash = data[:32]
+	ad.Flags = data[32]
+	ad.SignCount = binary.BigEndian.Uint32(data[33:37])
+
+	rpIDHash := sha256.Sum256([]byte(wa.RPID))
+	if !bytes.Equal(ad.RPIDHash, rpIDHash[:]) || ad.Flags&flagUserPresent == 0 {
+		return ad, ErrWebAuthnAuthData
+	}
+
+	if ad.Flags&flagAttestedData != 0 {
+		rest := data[37:]
+		// aaguid (16) and credential id length (2)
+		if len(rest) < 18 {
+			return ad, ErrWebAuthnAuthData
+		}
+		idLen := int(binary.BigEndian.Uint16(rest[16:18]))
+		rest = rest[18:]
+		if len(rest) < idLen {
+			return ad, ErrWebAuthnAuthData
+		}
+		ad.CredentialID = rest[:idLen]
+		rest = rest[idLen:]
+		_, n, err := decodeCBOR(rest)
+		if err != nil {
+			return ad, err
+		}
+		ad.PublicKey = rest[:n]
+	}
+	return ad, nil
+}
+
+// parseCOSEKey reads an EC2 P-256 public key (RFC 9053).
+func parseCOSEKey(raw []byte) (*ecdsa.PublicKey, error) {
+	obj, _, err := decodeCBOR(raw)
+	if err != nil {
+		return nil, err
+	}
+	key, ok := obj.(map[interface{}]interface{})
+	if !ok {
+		return nil, errCBOR
+	}
+	kty, _ := key[int64(1)].(int64)
+	alg, _ := key[int64(3)].(int64)
+	crv, _ := key[int64(-1)].(int64)
+	x, _ := key[int64(-2)].([]byte)
+	y, _ := key[int64(-3)].([]byte)
+	if kty != 2 || alg != coseAlgES256 || crv != 1 || len(x) != 32 || len(y) != 32 {
+		return nil, errors.New("webauthn: unsupported public key, only ES256 is accepted")
+	}
+	// ecdh rejects points that are not on the curve.
+	if _, err := ecdh.P256().NewPublicKey(append(append([]byte{4}, x...), y...)); err != nil {
+		return nil, err
+	}
+	return &ecdsa.PublicKey{
+		Curve: elliptic.P256(),
+		X:     new(big.Int).SetBytes(x),
+		Y:     new(big.Int).SetBytes(y),
+	}, nil
+}
@@ -0,0 +1,204 @@
+package auth
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"sort"
+	"testing"
+)
+
+// softAuthenticator is a software passkey: an ES256 key pair plus a counter,
+// producing the same bytes a browser would hand to the relying party.
+type softAuthenticator struct {
+	key       *ecdsa.PrivateKey
+	credID    []byte
+	signCount uint32
+}
+
+func newSoftAuthenticator(t *testing.T) *softAuthenticator {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey failed %v", err)
+	}
+	credID := make([]byte, 16)
+	rand.Read(credID)
+	return &softAuthenticator{key: key, credID: credID}
+}
+
+func (a *softAuthenticator) clientData(ceremony, origin string, challenge []byte) []byte {
+	data, _ := json.Marshal(map[string]string{
+		"type":      ceremony,
+		"challenge": base64.RawURLEncoding.EncodeToString(challenge),
+		"origin":    origin,
+	})
+	return data
+}
+
+func (a *softAuthenticator) authData(rpID string, flags byte, attested []byte) []byte {
+	rpIDHash := sha256.Sum256([]byte(rpID))
+	buf := append([]byte{}, rpIDHash[:]...)
+	buf = append(buf, flags)
+	buf = binary.BigEndian.AppendUint32(buf, a.signCount)
+	return append(buf, attested...)
+}
+
+func (a *softAuthenticator) create(rpID, origin string, challenge []byte) (clientDataJSON, attestationObject []byte) {
+	pub := a.key.PublicKey
+	cose := encodeCBOR(map[int64]interface{}{
+		1:  int64(2),
+		3:  int64(coseAlgES256),
+		-1: int64(1),
+		-2: pub.X.FillBytes(make([]byte, 32)),
+		-3: pub.Y.FillBytes(make([]byte, 32)),
+	})
+	attested := make([]byte, 16)
+	attested = binary.BigEndian.AppendUint16(attested, uint16(len(a.credID)))
+	attested = append(attested, a.credID...)
+	attested = append(attested, cose...)
+
+	authData := a.authData(rpID, flagUserPresent|flagAttestedData, attested)
+	attestationObject = encodeCBOR(map[string]interface{}{
+		"fmt":      "none",
+		"attStmt":  map[string]interface{}{},
+		"authData": authData,
+	})
+	return a.clientData("webauthn.create", origin, challenge), attestationObject
+}
+
+func (a *softAuthenticator) get(rpID, origin string, challenge []byte) (clientDataJSON, authData, signature []byte) {
+	a.signCount++
+	clientDataJSON = a.clientData("webauthn.get", origin, challenge)
+	authData = a.authData(rpID, flagUserPresent, nil)
+	clientDataHash := sha256.Sum256(clientDataJSON)
+	digest := sha256.Sum256(append(append([]byte{}, authData...), clientDataHash[:]...))
+	signature, _ = ecdsa.SignASN1(rand.Reader, a.key, digest[:])
+	return clientDataJSON, authData, signature
+}
+
+// encodeCBOR covers the handful of types the soft authenticator emits.
+func encodeCBOR(v interface{}) []byte {
+	head := func(major byte, n uint64) []byte {
+		switch {
+		case n < 24:
+			return []byte{major<<5 | byte(n)}
+		case n < 256:
+			return []byte{major<<5 | 24, byte(n)}
+		default:
+			return binary.BigEndian.AppendUint16([]byte{major<<5 | 25}, uint16(n))
+		}
+	}
+	switch v := v.(type) {
+	case int64:
+		if v < 0 {
+			return head(1, uint64(-1-v))
+		}
+		return head(0, uint64(v))
+	case []byte:
+		return append(head(2, uint64(len(v))), v...)
+	case string:
+		return append(head(3, uint64(len(v))), v...)
+	case map[int64]interface{}:
+		keys := make([]int64, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
+		out := head(5, uint64(len(v)))
+		for _, k := range keys {
+			out = append(out, encodeCBOR(k)...)
+			out = append(out, encodeCBOR(v[k])...)
+		}
+		return out
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		out := head(5, uint64(len(v)))
+		for _, k := range keys {
+			out = append(out, encodeCBOR(k)...)
+			out = append(out, encodeCBOR(v[k])...)
+		}
+		return out
+	}
+	panic("encodeCBOR: unsupported type")
+}
+
+func TestWebAuthnCeremonies(t *testing.T) {
+	wa := WebAuthn{RPID: "localhost", RPName: "Chirpy", Origin: "http://localhost:8080"}
+	authenticator := newSoftAuthenticator(t)
+
+	challenge, err := MakeWebAuthnChallenge()
+	if err != nil {
+		t.Fatalf("MakeWebAuthnChallenge failed %v", err)
+	}
+	clientDataJSON, attestationObject := authenticator.create(wa.RPID, wa.Origin, challenge)
+	cred, err := wa.VerifyRegistration(challenge, clientDataJSON, attestationObject)
+	if err != nil {
+		t.Fatalf("VerifyRegistration() had an error %v", err)
+	}
+	if !bytes.Equal(cred.ID, authenticator.credID) {
+		t.Errorf("expected credential id %x, got %x", authenticator.credID, cred.ID)
+	}
+
+	otherChallenge, _ := MakeWebAuthnChallenge()
+	if _, err := wa.VerifyRegistration(otherChallenge, clientDataJSON, attestationObject); !errors.Is(err, ErrWebAuthnClientData) {
+		t.Errorf("expected ErrWebAuthnClientData for a foreign challenge, got %v", err)
+	}
+
+	challenge, _ = MakeWebAuthnChallenge()
+	clientDataJSON, authData, signature := authenticator.get(wa.RPID, wa.Origin, challenge)
+	count, err := wa.VerifyAssertion(challenge, cred, clientDataJSON, authData, signature)
+	if err != nil {
+		t.Fatalf("VerifyAssertion() had an error %v", err)
+	}
+	if count != 1 {
+		t.Errorf("expected sign count 1, got %d", count)
+	}
+	cred.SignCount = count
+
+	t.Run("replayed assertion", func(t *testing.T) {
+		_, err := wa.VerifyAssertion(challenge, cred, clientDataJSON, authData, signature)
+		if !errors.Is(err, ErrWebAuthnSignCount) {
+			t.Errorf("expected ErrWebAuthnSignCount, got %v", err)
+		}
+	})
+
+	t.Run("wrong origin", func(t *testing.T) {
+		challenge, _ := MakeWebAuthnChallenge()
+		clientDataJSON, authData, signature := authenticator.get(wa.RPID, "https://evil.example", challenge)
+		_, err := wa.VerifyAssertion(challenge, cred, clientDataJSON, authData, signature)
+		if !errors.Is(err, ErrWebAuthnClientData) {
+			t.Errorf("expected ErrWebAuthnClientData, got %v", err)
+		}
+	})
+
+	t.Run("wrong relying party", func(t *testing.T) {
+		challenge, _ := MakeWebAuthnChallenge()
+		clientDataJSON, authData, signature := authenticator.get("evil.example", wa.Origin, challenge)
+		_, err := wa.VerifyAssertion(challenge, cred, clientDataJSON, authData, signature)
+		if !errors.Is(err, ErrWebAuthnAuthData) {
+			t.Errorf("expected ErrWebAuthnAuthData, got %v", err)
+		}
+	})
+
+	t.Run("signature from another key", func(t *testing.T) {
+		other := newSoftAuthenticator(t)
+		other.signCount = 10
+		challenge, _ := MakeWebAuthnChallenge()
+		clientDataJSON, authData, signature := other.get(wa.RPID, wa.Origin, challenge)
+		_, err := wa.VerifyAssertion(challenge, cred, clientDataJSON, authData, signature)
+		if !errors.Is(err, ErrWebAuthnSignature) {
+			t.Errorf("expected ErrWebAuthnSignature, got %v", err)
+		}
+	})
+}
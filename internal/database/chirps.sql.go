@@ -7,22 +7,73 @@ package database
 
 import (
 	"context"
+	"database/sql"
+	"time"
 
 	"github.com/google/uuid"
 )
 
-const createChirp = `-- name: CreateChirp :one
-INSERT INTO chirps (id, created_at, updated_at, body, user_id)
-VALUES (gen_random_uuid(), NOW(), NOW(), $1, $2) RETURNING id, created_at, updated_at, body, user_id
+const countChirpsByUserSince = `-- name: CountChirpsByUserSince :one
+SELECT COUNT(*) FROM chirps WHERE user_id = $1 AND created_at >= $2
 `
 
-type CreateChirpParams struct {
+type CountChirpsByUserSinceParams struct {
+	UserID    uuid.UUID
+	CreatedAt time.Time
+}
+
+func (q *Queries) CountChirpsByUserSince(ctx context.Context, arg CountChirpsByUserSinceParams) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countChirpsByUserSince, arg.UserID, arg.CreatedAt)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const countDuplicateAuthors = `-- name: CountDuplicateAuthors :one
+SELECT COUNT(DISTINCT user_id) FROM chirps
+WHERE md5(lower(body)) = md5(lower($1::text))
+AND user_id <> $2::uuid
+AND created_at >= $3::timestamp
+`
+
+type CountDuplicateAuthorsParams struct {
 	Body   string
 	UserID uuid.UUID
+	Since  time.Time
+}
+
+func (q *Queries) CountDuplicateAuthors(ctx context.Context, arg CountDuplicateAuthorsParams) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countDuplicateAuthors, arg.Body, arg.UserID, arg.Since)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const createChirp = `-- name: CreateChirp :one
+INSERT INTO chirps (id, created_at, updated_at, body, user_id, needs_review, review_reason, hidden_at, content_warning, sensitive)
+VALUES (gen_random_uuid(), NOW(), NOW(), $1, $2, $3, $4, $5, $6, $7) RETURNING id, created_at, updated_at, body, user_id, needs_review, review_reason, hidden_at, content_warning, sensitive
+`
+
+type CreateChirpParams struct {
+	Body           string
+	UserID         uuid.UUID
+	NeedsReview    bool
+	ReviewReason   string
+	HiddenAt       sql.NullTime
+	ContentWarning string
+	Sensitive      bool
 }
 
 func (q *Queries) CreateChirp(ctx context.Context, arg CreateChirpParams) (Chirp, error) {
-	row := q.db.QueryRowContext(ctx, createChirp, arg.Body, arg.UserID)
+	row := q.db.QueryRowContext(ctx, createChirp,
+		arg.Body,
+		arg.UserID,
+		arg.NeedsReview,
+		arg.ReviewReason,
+		arg.HiddenAt,
+		arg.ContentWarning,
+		arg.Sensitive,
+	)
 	var i Chirp
 	err := row.Scan(
 		&i.ID,
@@ -30,6 +81,11 @@ func (q *Queries) CreateChirp(ctx context.Context, arg CreateChirpParams) (Chirp
 		&i.UpdatedAt,
 		&i.Body,
 		&i.UserID,
+		&i.NeedsReview,
+		&i.ReviewReason,
+		&i.HiddenAt,
+		&i.ContentWarning,
+		&i.Sensitive,
 	)
 	return i, err
 }
@@ -44,7 +100,10 @@ func (q *Queries) DeleteChirpById(ctx context.Context, id uuid.UUID) error {
 }
 
 const getAllChirps = `-- name: GetAllChirps :many
-SELECT id, created_at, updated_at, body, user_id FROM chirps ORDER BY created_at ASC
+SELECT id, created_at, updated_at, body, user_id, needs_review, review_reason, hidden_at, content_warning, sensitive FROM chirps c
+WHERE c.hidden_at IS NULL
+AND NOT EXISTS (SELECT 1 FROM users u WHERE u.id = c.user_id AND u.limited_at IS NOT NULL)
+ORDER BY c.created_at ASC
 `
 
 func (q *Queries) GetAllChirps(ctx context.Context) ([]Chirp, error) {
@@ -62,6 +121,11 @@ func (q *Queries) GetAllChirps(ctx context.Context) ([]Chirp, error) {
 			&i.UpdatedAt,
 			&i.Body,
 			&i.UserID,
+			&i.NeedsReview,
+			&i.ReviewReason,
+			&i.HiddenAt,
+			&i.ContentWarning,
+			&i.Sensitive,
 		); err != nil {
 			return nil, err
 		}
@@ -77,7 +141,7 @@ func (q *Queries) GetAllChirps(ctx context.Context) ([]Chirp, error) {
 }
 
 const getChirpById = `-- name: GetChirpById :one
-SELECT id, created_at, updated_at, body, user_id FROM chirps WHERE ID = $1
+SELECT id, created_at, updated_at, body, user_id, needs_review, review_reason, hidden_at, content_warning, sensitive FROM chirps WHERE ID = $1
 `
 
 func (q *Queries) GetChirpById(ctx context.Context, id uuid.UUID) (Chirp, error) {
@@ -89,6 +153,149 @@ func (q *Queries) GetChirpById(ctx context.Context, id uuid.UUID) (Chirp, error)
 		&i.UpdatedAt,
 		&i.Body,
 		&i.UserID,
+		&i.NeedsReview,
+		&i.ReviewReason,
+		&i.HiddenAt,
+		&i.ContentWarning,
+		&i.Sensitive,
+	)
+	return i, err
+}
+
+const getChirpsByUser = `-- name: GetChirpsByUser :many
+SELECT id, created_at, updated_at, body, user_id, needs_review, review_reason, hidden_at, content_warning, sensitive FROM chirps WHERE user_id = $1 ORDER BY created_at ASC
+`
+
+func (q *Queries) GetChirpsByUser(ctx context.Context, userID uuid.UUID) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, getChirpsByUser, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.NeedsReview,
+			&i.ReviewReason,
+			&i.HiddenAt,
+			&i.ContentWarning,
+			&i.Sensitive,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getChirpsForViewer = `-- name: GetChirpsForViewer :many
+SELECT id, created_at, updated_at, body, user_id, needs_review, review_reason, hidden_at, content_warning, sensitive FROM chirps c
+WHERE (
+	c.user_id = $1::uuid
+	OR (
+		c.hidden_at IS NULL
+		AND NOT EXISTS (SELECT 1 FROM users u WHERE u.id = c.user_id AND u.limited_at IS NOT NULL)
+	)
+)
+AND NOT EXISTS (
+	SELECT 1 FROM user_blocks b
+	WHERE (b.blocker_id = $1::uuid AND b.blocked_id = c.user_id)
+	OR (b.blocker_id = c.user_id AND b.blocked_id = $1::uuid)
+)
+AND NOT EXISTS (
+	SELECT 1 FROM user_mutes m WHERE m.muter_id = $1::uuid AND m.muted_id = c.user_id
+)
+ORDER BY c.created_at ASC
+`
+
+func (q *Queries) GetChirpsForViewer(ctx context.Context, viewerID uuid.UUID) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, getChirpsForViewer, viewerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.NeedsReview,
+			&i.ReviewReason,
+			&i.HiddenAt,
+			&i.ContentWarning,
+			&i.Sensitive,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const hideChirp = `-- name: HideChirp :exec
+UPDATE chirps SET hidden_at = NOW(), updated_at = NOW() WHERE id = $1
+`
+
+func (q *Queries) HideChirp(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, hideChirp, id)
+	return err
+}
+
+const setChirpSensitivity = `-- name: SetChirpSensitivity :one
+UPDATE chirps SET content_warning = $1, sensitive = $2, updated_at = NOW() WHERE id = $3 RETURNING id, created_at, updated_at, body, user_id, needs_review, review_reason, hidden_at, content_warning, sensitive
+`
+
+type SetChirpSensitivityParams struct {
+	ContentWarning string
+	Sensitive      bool
+	ID             uuid.UUID
+}
+
+func (q *Queries) SetChirpSensitivity(ctx context.Context, arg SetChirpSensitivityParams) (Chirp, error) {
+	row := q.db.QueryRowContext(ctx, setChirpSensitivity, arg.ContentWarning, arg.Sensitive, arg.ID)
+	var i Chirp
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Body,
+		&i.UserID,
+		&i.NeedsReview,
+		&i.ReviewReason,
+		&i.HiddenAt,
+		&i.ContentWarning,
+		&i.Sensitive,
 	)
 	return i, err
 }
+
+const unhideChirp = `-- name: UnhideChirp :exec
+UPDATE chirps SET hidden_at = NULL, needs_review = false, updated_at = NOW() WHERE id = $1
+`
+
+func (q *Queries) UnhideChirp(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, unhideChirp, id)
+	return err
+}
@@ -6,17 +6,153 @@ package database
 
 import (
 	"database/sql"
+	"encoding/json"
 	"time"
 
 	"github.com/google/uuid"
 )
 
+type ApiKey struct {
+	ID         uuid.UUID
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+	UserID     uuid.UUID
+	Name       string
+	KeyHash    string
+	Scopes     []string
+	ExpiresAt  sql.NullTime
+	LastUsedAt sql.NullTime
+}
+
+type AuditEvent struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	Action    string
+	ActorID   uuid.NullUUID
+	TargetID  uuid.NullUUID
+	Ip        string
+	UserAgent string
+	Metadata  json.RawMessage
+}
+
 type Chirp struct {
+	ID             uuid.UUID
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+	Body           string
+	UserID         uuid.UUID
+	NeedsReview    bool
+	ReviewReason   string
+	HiddenAt       sql.NullTime
+	ContentWarning string
+	Sensitive      bool
+}
+
+type ChirpLink struct {
+	ChirpID    uuid.UUID
+	Position   int32
+	Url        string
+	StartIndex int32
+	EndIndex   int32
+}
+
+type ChirpReaction struct {
+	ChirpID   uuid.UUID
+	UserID    uuid.UUID
+	Emoji     string
+	CreatedAt time.Time
+}
+
+type ChirpReactionCount struct {
+	ChirpID uuid.UUID
+	Emoji   string
+	Count   int32
+}
+
+type KeywordFilter struct {
 	ID        uuid.UUID
 	CreatedAt time.Time
 	UpdatedAt time.Time
-	Body      string
 	UserID    uuid.UUID
+	Phrase    string
+	WholeWord bool
+	Contexts  []string
+	ExpiresAt sql.NullTime
+}
+
+type LinkPreview struct {
+	Url         string
+	CreatedAt   time.Time
+	Status      string
+	Title       string
+	Description string
+	ImageUrl    string
+	SiteName    string
+	Error       string
+	FetchedAt   sql.NullTime
+}
+
+type LoginAttempt struct {
+	AttemptKey    string
+	Failures      int32
+	LastFailureAt time.Time
+	LockedUntil   sql.NullTime
+}
+
+type ModerationRule struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	Term      string
+	Action    string
+}
+
+type OauthAccessToken struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	ClientID  uuid.UUID
+	UserID    uuid.UUID
+	Scopes    []string
+	ExpiresAt time.Time
+	RevokedAt sql.NullTime
+}
+
+type OauthClient struct {
+	ID           uuid.UUID
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+	OwnerID      uuid.UUID
+	Name         string
+	SecretHash   sql.NullString
+	RedirectUris []string
+}
+
+type OauthCode struct {
+	CodeHash      string
+	CreatedAt     time.Time
+	ClientID      uuid.UUID
+	UserID        uuid.UUID
+	RedirectUri   string
+	Scopes        []string
+	CodeChallenge string
+	ExpiresAt     time.Time
+	UsedAt        sql.NullTime
+}
+
+type PasswordResetToken struct {
+	TokenHash string
+	CreatedAt time.Time
+	UserID    uuid.UUID
+	ExpiresAt time.Time
+	UsedAt    sql.NullTime
+}
+
+type RecoveryCode struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	UserID    uuid.UUID
+	CodeHash  string
+	UsedAt    sql.NullTime
 }
 
 type RefreshToken struct {
@@ -28,10 +164,72 @@ type RefreshToken struct {
 	RevokedAt sql.NullTime
 }
 
-type User struct {
+type Report struct {
 	ID             uuid.UUID
 	CreatedAt      time.Time
 	UpdatedAt      time.Time
-	Email          string
-	HashedPassword string
+	ReporterID     uuid.NullUUID
+	ReportedUserID uuid.UUID
+	ChirpID        uuid.NullUUID
+	ChirpBody      string
+	Reason         string
+	Details        string
+	Status         string
+	Resolution     string
+	ModeratorID    uuid.NullUUID
+	ModeratorNotes string
+	ResolvedAt     sql.NullTime
+	Kind           string
+}
+
+type User struct {
+	ID                uuid.UUID
+	CreatedAt         time.Time
+	UpdatedAt         time.Time
+	Email             string
+	HashedPassword    string
+	TotpSecret        sql.NullString
+	TotpEnabledAt     sql.NullTime
+	TotpLastStep      sql.NullInt64
+	EmailVerifiedAt   sql.NullTime
+	PendingEmail      sql.NullString
+	DeleteAfter       sql.NullTime
+	Role              string
+	SuspendedAt       sql.NullTime
+	SuspensionReason  string
+	MustResetPassword bool
+	LimitedAt         sql.NullTime
+	LimitReason       string
+	SensitiveContent  string
+}
+
+type UserBlock struct {
+	BlockerID uuid.UUID
+	BlockedID uuid.UUID
+	CreatedAt time.Time
+}
+
+type UserMute struct {
+	MuterID   uuid.UUID
+	MutedID   uuid.UUID
+	CreatedAt time.Time
+}
+
+type WebauthnChallenge struct {
+	Challenge []byte
+	CreatedAt time.Time
+	UserID    uuid.NullUUID
+	Ceremony  string
+	ExpiresAt time.Time
+}
+
+type WebauthnCredential struct {
+	ID           uuid.UUID
+	CreatedAt    time.Time
+	UserID       uuid.UUID
+	Name         string
+	CredentialID []byte
+	PublicKey    []byte
+	SignCount    int64
+	LastUsedAt   sql.NullTime
 }
@@ -0,0 +1,94 @@
+package database_test
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"testing"
+
+	"github.com/anton-jj/chripy/internal/database"
+	"github.com/google/uuid"
+	_ "github.com/lib/pq"
+)
+
+// testQueries connects to TEST_DATABASE_URL, a database with every migration
+// in sql/schema applied. Tests that need it are skipped when it isn't set.
+func testQueries(t *testing.T) *database.Queries {
+	t.Helper()
+	url := os.Getenv("TEST_DATABASE_URL")
+	if url == "" {
+		t.Skip("TEST_DATABASE_URL is not set")
+	}
+	db, err := sql.Open("postgres", url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return database.New(db)
+}
+
+func createTestUser(t *testing.T, q *database.Queries) database.User {
+	t.Helper()
+	ctx := context.Background()
+	user, err := q.CreateUser(ctx, database.CreateUserParams{
+		Email:          uuid.NewString() + "@example.com",
+		HashedPassword: "unused",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { q.DeleteUser(ctx, user.ID) })
+	return user
+}
+
+// A deleted chirp's reports lose their chirp_id but must not then collide
+// with the reporter's open user report, or with each other.
+func TestDeleteReportedChirps(t *testing.T) {
+	q := testQueries(t)
+	ctx := context.Background()
+	reporter := createTestUser(t, q)
+	author := createTestUser(t, q)
+
+	_, err := q.CreateReport(ctx, database.CreateReportParams{
+		ReporterID:     uuid.NullUUID{Valid: true, UUID: reporter.ID},
+		ReportedUserID: author.ID,
+		Reason:         "spam",
+		Kind:           "user",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var reports []database.Report
+	for _, body := range []string{"first", "second"} {
+		chirp, err := q.CreateChirp(ctx, database.CreateChirpParams{Body: body, UserID: author.ID})
+		if err != nil {
+			t.Fatal(err)
+		}
+		report, err := q.CreateReport(ctx, database.CreateReportParams{
+			ReporterID:     uuid.NullUUID{Valid: true, UUID: reporter.ID},
+			ReportedUserID: author.ID,
+			ChirpID:        uuid.NullUUID{Valid: true, UUID: chirp.ID},
+			ChirpBody:      chirp.Body,
+			Reason:         "spam",
+			Kind:           "chirp",
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		reports = append(reports, report)
+	}
+
+	for _, report := range reports {
+		if err := q.DeleteChirpById(ctx, report.ChirpID.UUID); err != nil {
+			t.Fatalf("deleting reported chirp: %v", err)
+		}
+		got, err := q.GetReport(ctx, report.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.ChirpID.Valid || got.Kind != "chirp" || got.Status != "open" || got.ChirpBody != report.ChirpBody {
+			t.Errorf("report after chirp deletion = %+v", got)
+		}
+	}
+}
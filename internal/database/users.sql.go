@@ -12,9 +12,50 @@ import (
 	"github.com/google/uuid"
 )
 
+const cancelUserDeletion = `-- name: CancelUserDeletion :execrows
+	UPDATE users SET delete_after = NULL, updated_at = NOW() WHERE id = $1 AND delete_after IS NOT NULL
+`
+
+func (q *Queries) CancelUserDeletion(ctx context.Context, id uuid.UUID) (int64, error) {
+	result, err := q.db.ExecContext(ctx, cancelUserDeletion, id)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const confirmEmailChange = `-- name: ConfirmEmailChange :execrows
+	UPDATE users SET email = pending_email, pending_email = NULL, email_verified_at = NOW(), updated_at = NOW() WHERE id = $1 AND pending_email = $2
+`
+
+type ConfirmEmailChangeParams struct {
+	ID           uuid.UUID
+	PendingEmail sql.NullString
+}
+
+func (q *Queries) ConfirmEmailChange(ctx context.Context, arg ConfirmEmailChangeParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, confirmEmailChange, arg.ID, arg.PendingEmail)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const countUsers = `-- name: CountUsers :one
+	SELECT COUNT(*) FROM users
+	WHERE $1::text = '' OR email ILIKE '%' || $1::text || '%'
+`
+
+func (q *Queries) CountUsers(ctx context.Context, search string) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countUsers, search)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
 const createUser = `-- name: CreateUser :one
 INSERT INTO users (id, created_at, updated_at, email, hashed_password) 
-VALUES (gen_random_uuid(), NOW(),  NOW(), $1, $2) RETURNING id, created_at, updated_at, email, hashed_password
+VALUES (gen_random_uuid(), NOW(),  NOW(), $1, $2) RETURNING id, created_at, updated_at, email, hashed_password, totp_secret, totp_enabled_at, totp_last_step, email_verified_at, pending_email, delete_after, role, suspended_at, suspension_reason, must_reset_password, limited_at, limit_reason, sensitive_content
 `
 
 type CreateUserParams struct {
@@ -31,12 +72,64 @@ func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (User, e
 		&i.UpdatedAt,
 		&i.Email,
 		&i.HashedPassword,
+		&i.TotpSecret,
+		&i.TotpEnabledAt,
+		&i.TotpLastStep,
+		&i.EmailVerifiedAt,
+		&i.PendingEmail,
+		&i.DeleteAfter,
+		&i.Role,
+		&i.SuspendedAt,
+		&i.SuspensionReason,
+		&i.MustResetPassword,
+		&i.LimitedAt,
+		&i.LimitReason,
+		&i.SensitiveContent,
 	)
 	return i, err
 }
 
+const deleteExpiredUsers = `-- name: DeleteExpiredUsers :execrows
+	DELETE FROM users WHERE delete_after IS NOT NULL AND delete_after <= NOW()
+`
+
+func (q *Queries) DeleteExpiredUsers(ctx context.Context) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteExpiredUsers)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const deleteUser = `-- name: DeleteUser :exec
+	DELETE FROM users WHERE id = $1
+`
+
+func (q *Queries) DeleteUser(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, deleteUser, id)
+	return err
+}
+
+const disableTOTP = `-- name: DisableTOTP :exec
+	UPDATE users SET totp_secret = NULL, totp_enabled_at = NULL, totp_last_step = NULL, updated_at = NOW() WHERE id = $1
+`
+
+func (q *Queries) DisableTOTP(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, disableTOTP, id)
+	return err
+}
+
+const enableTOTP = `-- name: EnableTOTP :exec
+	UPDATE users SET totp_enabled_at = NOW(), updated_at = NOW() WHERE id = $1
+`
+
+func (q *Queries) EnableTOTP(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, enableTOTP, id)
+	return err
+}
+
 const getUserByEmail = `-- name: GetUserByEmail :one
-	SELECT id, created_at, updated_at, email, hashed_password FROM users WHERE email = $1
+	SELECT id, created_at, updated_at, email, hashed_password, totp_secret, totp_enabled_at, totp_last_step, email_verified_at, pending_email, delete_after, role, suspended_at, suspension_reason, must_reset_password, limited_at, limit_reason, sensitive_content FROM users WHERE email = $1
 `
 
 func (q *Queries) GetUserByEmail(ctx context.Context, email string) (User, error) {
@@ -48,12 +141,25 @@ func (q *Queries) GetUserByEmail(ctx context.Context, email string) (User, error
 		&i.UpdatedAt,
 		&i.Email,
 		&i.HashedPassword,
+		&i.TotpSecret,
+		&i.TotpEnabledAt,
+		&i.TotpLastStep,
+		&i.EmailVerifiedAt,
+		&i.PendingEmail,
+		&i.DeleteAfter,
+		&i.Role,
+		&i.SuspendedAt,
+		&i.SuspensionReason,
+		&i.MustResetPassword,
+		&i.LimitedAt,
+		&i.LimitReason,
+		&i.SensitiveContent,
 	)
 	return i, err
 }
 
 const getUserById = `-- name: GetUserById :one
-	SELECT id, created_at, updated_at, email, hashed_password FROM users WHERE id = $1
+	SELECT id, created_at, updated_at, email, hashed_password, totp_secret, totp_enabled_at, totp_last_step, email_verified_at, pending_email, delete_after, role, suspended_at, suspension_reason, must_reset_password, limited_at, limit_reason, sensitive_content FROM users WHERE id = $1
 `
 
 func (q *Queries) GetUserById(ctx context.Context, id uuid.UUID) (User, error) {
@@ -65,12 +171,25 @@ func (q *Queries) GetUserById(ctx context.Context, id uuid.UUID) (User, error) {
 		&i.UpdatedAt,
 		&i.Email,
 		&i.HashedPassword,
+		&i.TotpSecret,
+		&i.TotpEnabledAt,
+		&i.TotpLastStep,
+		&i.EmailVerifiedAt,
+		&i.PendingEmail,
+		&i.DeleteAfter,
+		&i.Role,
+		&i.SuspendedAt,
+		&i.SuspensionReason,
+		&i.MustResetPassword,
+		&i.LimitedAt,
+		&i.LimitReason,
+		&i.SensitiveContent,
 	)
 	return i, err
 }
 
 const getUserFromRefreshToken = `-- name: GetUserFromRefreshToken :one
-	SELECT u.id, u.created_at, u.updated_at, u.email, u.hashed_password FROM users u JOIN refresh_tokens rt ON u.id = rt.user_id WHERE rt.token = $1
+	SELECT u.id, u.created_at, u.updated_at, u.email, u.hashed_password, u.totp_secret, u.totp_enabled_at, u.totp_last_step, u.email_verified_at, u.pending_email, u.delete_after, u.role, u.suspended_at, u.suspension_reason, u.must_reset_password, u.limited_at, u.limit_reason, u.sensitive_content FROM users u JOIN refresh_tokens rt ON u.id = rt.user_id WHERE rt.token = $1
 `
 
 func (q *Queries) GetUserFromRefreshToken(ctx context.Context, token sql.NullString) (User, error) {
@@ -82,10 +201,120 @@ func (q *Queries) GetUserFromRefreshToken(ctx context.Context, token sql.NullStr
 		&i.UpdatedAt,
 		&i.Email,
 		&i.HashedPassword,
+		&i.TotpSecret,
+		&i.TotpEnabledAt,
+		&i.TotpLastStep,
+		&i.EmailVerifiedAt,
+		&i.PendingEmail,
+		&i.DeleteAfter,
+		&i.Role,
+		&i.SuspendedAt,
+		&i.SuspensionReason,
+		&i.MustResetPassword,
+		&i.LimitedAt,
+		&i.LimitReason,
+		&i.SensitiveContent,
 	)
 	return i, err
 }
 
+const limitUser = `-- name: LimitUser :exec
+	UPDATE users SET limited_at = NOW(), limit_reason = $1, updated_at = NOW() WHERE id = $2
+`
+
+type LimitUserParams struct {
+	LimitReason string
+	ID          uuid.UUID
+}
+
+func (q *Queries) LimitUser(ctx context.Context, arg LimitUserParams) error {
+	_, err := q.db.ExecContext(ctx, limitUser, arg.LimitReason, arg.ID)
+	return err
+}
+
+const listUsers = `-- name: ListUsers :many
+	SELECT id, created_at, updated_at, email, hashed_password, totp_secret, totp_enabled_at, totp_last_step, email_verified_at, pending_email, delete_after, role, suspended_at, suspension_reason, must_reset_password, limited_at, limit_reason, sensitive_content FROM users
+	WHERE $1::text = '' OR email ILIKE '%' || $1::text || '%'
+	ORDER BY created_at ASC, id ASC
+	LIMIT $2 OFFSET $3
+`
+
+type ListUsersParams struct {
+	Search     string
+	MaxResults int32
+	Skip       int32
+}
+
+func (q *Queries) ListUsers(ctx context.Context, arg ListUsersParams) ([]User, error) {
+	rows, err := q.db.QueryContext(ctx, listUsers, arg.Search, arg.MaxResults, arg.Skip)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []User
+	for rows.Next() {
+		var i User
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Email,
+			&i.HashedPassword,
+			&i.TotpSecret,
+			&i.TotpEnabledAt,
+			&i.TotpLastStep,
+			&i.EmailVerifiedAt,
+			&i.PendingEmail,
+			&i.DeleteAfter,
+			&i.Role,
+			&i.SuspendedAt,
+			&i.SuspensionReason,
+			&i.MustResetPassword,
+			&i.LimitedAt,
+			&i.LimitReason,
+			&i.SensitiveContent,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const rehashUserPassword = `-- name: RehashUserPassword :execrows
+	UPDATE users SET hashed_password = $1::text
+	WHERE id = $2::uuid AND hashed_password = $3::text
+`
+
+type RehashUserPasswordParams struct {
+	NewHash string
+	ID      uuid.UUID
+	OldHash string
+}
+
+func (q *Queries) RehashUserPassword(ctx context.Context, arg RehashUserPasswordParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, rehashUserPassword, arg.NewHash, arg.ID, arg.OldHash)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const requirePasswordReset = `-- name: RequirePasswordReset :exec
+	UPDATE users SET must_reset_password = true, updated_at = NOW() WHERE id = $1
+`
+
+func (q *Queries) RequirePasswordReset(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, requirePasswordReset, id)
+	return err
+}
+
 const resetDatabase = `-- name: ResetDatabase :exec
 DELETE from users
 `
@@ -95,17 +324,155 @@ func (q *Queries) ResetDatabase(ctx context.Context) error {
 	return err
 }
 
-const updateUser = `-- name: UpdateUser :exec
-	UPDATE users SET email = $1, hashed_password = $2 WHERE id = $3
+const scheduleUserDeletion = `-- name: ScheduleUserDeletion :exec
+	UPDATE users SET delete_after = $1, updated_at = NOW() WHERE id = $2
 `
 
-type UpdateUserParams struct {
-	Email          string
+type ScheduleUserDeletionParams struct {
+	DeleteAfter sql.NullTime
+	ID          uuid.UUID
+}
+
+func (q *Queries) ScheduleUserDeletion(ctx context.Context, arg ScheduleUserDeletionParams) error {
+	_, err := q.db.ExecContext(ctx, scheduleUserDeletion, arg.DeleteAfter, arg.ID)
+	return err
+}
+
+const setPendingEmail = `-- name: SetPendingEmail :exec
+	UPDATE users SET pending_email = $1, updated_at = NOW() WHERE id = $2
+`
+
+type SetPendingEmailParams struct {
+	PendingEmail sql.NullString
+	ID           uuid.UUID
+}
+
+func (q *Queries) SetPendingEmail(ctx context.Context, arg SetPendingEmailParams) error {
+	_, err := q.db.ExecContext(ctx, setPendingEmail, arg.PendingEmail, arg.ID)
+	return err
+}
+
+const setSensitiveContentPreference = `-- name: SetSensitiveContentPreference :exec
+	UPDATE users SET sensitive_content = $1, updated_at = NOW() WHERE id = $2
+`
+
+type SetSensitiveContentPreferenceParams struct {
+	SensitiveContent string
+	ID               uuid.UUID
+}
+
+func (q *Queries) SetSensitiveContentPreference(ctx context.Context, arg SetSensitiveContentPreferenceParams) error {
+	_, err := q.db.ExecContext(ctx, setSensitiveContentPreference, arg.SensitiveContent, arg.ID)
+	return err
+}
+
+const setTOTPSecret = `-- name: SetTOTPSecret :exec
+	UPDATE users SET totp_secret = $1, totp_enabled_at = NULL, updated_at = NOW() WHERE id = $2
+`
+
+type SetTOTPSecretParams struct {
+	TotpSecret sql.NullString
+	ID         uuid.UUID
+}
+
+func (q *Queries) SetTOTPSecret(ctx context.Context, arg SetTOTPSecretParams) error {
+	_, err := q.db.ExecContext(ctx, setTOTPSecret, arg.TotpSecret, arg.ID)
+	return err
+}
+
+const setUserRole = `-- name: SetUserRole :execrows
+	UPDATE users SET role = $1, updated_at = NOW() WHERE email = $2
+`
+
+type SetUserRoleParams struct {
+	Role  string
+	Email string
+}
+
+func (q *Queries) SetUserRole(ctx context.Context, arg SetUserRoleParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, setUserRole, arg.Role, arg.Email)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const suspendUser = `-- name: SuspendUser :exec
+	UPDATE users SET suspended_at = NOW(), suspension_reason = $1, updated_at = NOW() WHERE id = $2
+`
+
+type SuspendUserParams struct {
+	SuspensionReason string
+	ID               uuid.UUID
+}
+
+func (q *Queries) SuspendUser(ctx context.Context, arg SuspendUserParams) error {
+	_, err := q.db.ExecContext(ctx, suspendUser, arg.SuspensionReason, arg.ID)
+	return err
+}
+
+const unlimitUser = `-- name: UnlimitUser :exec
+	UPDATE users SET limited_at = NULL, limit_reason = '', updated_at = NOW() WHERE id = $1
+`
+
+func (q *Queries) UnlimitUser(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, unlimitUser, id)
+	return err
+}
+
+const unsuspendUser = `-- name: UnsuspendUser :exec
+	UPDATE users SET suspended_at = NULL, suspension_reason = '', updated_at = NOW() WHERE id = $1
+`
+
+func (q *Queries) UnsuspendUser(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, unsuspendUser, id)
+	return err
+}
+
+const updateTOTPLastStep = `-- name: UpdateTOTPLastStep :execrows
+	UPDATE users SET totp_last_step = $1 WHERE id = $2 AND (totp_last_step IS NULL OR totp_last_step < $1)
+`
+
+type UpdateTOTPLastStepParams struct {
+	TotpLastStep sql.NullInt64
+	ID           uuid.UUID
+}
+
+func (q *Queries) UpdateTOTPLastStep(ctx context.Context, arg UpdateTOTPLastStepParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, updateTOTPLastStep, arg.TotpLastStep, arg.ID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const updateUserPassword = `-- name: UpdateUserPassword :exec
+	UPDATE users SET hashed_password = $1, must_reset_password = false, updated_at = NOW() WHERE id = $2
+`
+
+type UpdateUserPasswordParams struct {
 	HashedPassword string
 	ID             uuid.UUID
 }
 
-func (q *Queries) UpdateUser(ctx context.Context, arg UpdateUserParams) error {
-	_, err := q.db.ExecContext(ctx, updateUser, arg.Email, arg.HashedPassword, arg.ID)
+func (q *Queries) UpdateUserPassword(ctx context.Context, arg UpdateUserPasswordParams) error {
+	_, err := q.db.ExecContext(ctx, updateUserPassword, arg.HashedPassword, arg.ID)
 	return err
 }
+
+const verifyUserEmail = `-- name: VerifyUserEmail :execrows
+	UPDATE users SET email_verified_at = NOW(), updated_at = NOW() WHERE id = $1 AND email = $2
+`
+
+type VerifyUserEmailParams struct {
+	ID    uuid.UUID
+	Email string
+}
+
+func (q *Queries) VerifyUserEmail(ctx context.Context, arg VerifyUserEmailParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, verifyUserEmail, arg.ID, arg.Email)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
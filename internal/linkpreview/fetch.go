@@ -0,0 +1,259 @@
+package linkpreview
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"regexp"
+	"strings"
+	"syscall"
+	"time"
+	"unicode/utf8"
+)
+
+var (
+	ErrBlockedAddress = errors.New("linkpreview: address is not public")
+	ErrNotHTML        = errors.New("linkpreview: response is not an HTML page")
+	ErrNoMetadata     = errors.New("linkpreview: page has no title or description")
+)
+
+const (
+	DefaultTimeout  = 5 * time.Second
+	DefaultMaxBytes = 512 << 10
+	maxRedirects    = 5
+	maxTitle        = 200
+	maxDescription  = 300
+)
+
+// Options configures an HTTPFetcher. Zero values pick the defaults.
+type Options struct {
+	// Timeout bounds the whole fetch, redirects included.
+	Timeout time.Duration
+	// MaxBytes is how much of a page is read. Metadata lives in the head, so
+	// a longer page is parsed from its first MaxBytes.
+	MaxBytes  int64
+	UserAgent string
+	// AllowAddr decides which addresses may be connected to. It defaults to
+	// PublicAddr; tests against an httptest server on loopback override it.
+	AllowAddr func(netip.Addr) bool
+}
+
+// HTTPFetcher fetches previews over the network.
+type HTTPFetcher struct {
+	client    *http.Client
+	maxBytes  int64
+	userAgent string
+}
+
+func NewHTTPFetcher(opts Options) *HTTPFetcher {
+	if opts.Timeout <= 0 {
+		opts.Timeout = DefaultTimeout
+	}
+	if opts.MaxBytes <= 0 {
+		opts.MaxBytes = DefaultMaxBytes
+	}
+	if opts.UserAgent == "" {
+		opts.UserAgent = "ChirpyBot/1.0 (link previews)"
+	}
+	if opts.AllowAddr == nil {
+		opts.AllowAddr = PublicAddr
+	}
+
+	// The address is checked in Control, after DNS resolution and for every
+	// connection including redirects, so a hostname that resolves (or later
+	// re-resolves) to a private address is refused too.
+	dialer := &net.Dialer{
+		Timeout: opts.Timeout,
+		Control: func(network, address string, c syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			addr, err := netip.ParseAddr(host)
+			if err != nil || !opts.AllowAddr(addr.Unmap()) {
+				return ErrBlockedAddress
+			}
+			return nil
+		},
+	}
+	transport := &http.Transport{
+		// No proxy: a proxy would make the connection on our behalf and
+		// bypass the address check.
+		Proxy:                 nil,
+		DialContext:           dialer.DialContext,
+		TLSHandshakeTimeout:   opts.Timeout,
+		ResponseHeaderTimeout: opts.Timeout,
+		MaxIdleConns:          10,
+		IdleConnTimeout:       30 * time.Second,
+	}
+	return &HTTPFetcher{
+		client: &http.Client{
+			Transport: transport,
+			Timeout:   opts.Timeout,
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				if len(via) >= maxRedirects {
+					return errors.New("linkpreview: too many redirects")
+				}
+				if req.URL.Scheme != "http" && req.URL.Scheme != "https" {
+					return fmt.Errorf("linkpreview: redirect to unsupported scheme %q", req.URL.Scheme)
+				}
+				return nil
+			},
+		},
+		maxBytes:  opts.MaxBytes,
+		userAgent: opts.UserAgent,
+	}
+}
+
+func (f *HTTPFetcher) Fetch(ctx context.Context, rawURL string) (Preview, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return Preview{}, err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return Preview{}, fmt.Errorf("linkpreview: unsupported scheme %q", u.Scheme)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return Preview{}, err
+	}
+	req.Header.Set("User-Agent", f.userAgent)
+	req.Header.Set("Accept", "text/html,application/xhtml+xml")
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return Preview{}, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return Preview{}, fmt.Errorf("linkpreview: %s returned %s", u.Host, resp.Status)
+	}
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if mediaType != "text/html" && mediaType != "application/xhtml+xml" {
+		return Preview{}, ErrNotHTML
+	}
+	page, err := io.ReadAll(io.LimitReader(resp.Body, f.maxBytes))
+	if err != nil {
+		return Preview{}, err
+	}
+
+	p := Parse(string(page), resp.Request.URL)
+	p.URL = rawURL
+	if p.Title == "" && p.Description == "" {
+		return Preview{}, ErrNoMetadata
+	}
+	return p, nil
+}
+
+var blockedPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"), // carrier-grade NAT
+	netip.MustParsePrefix("192.0.0.0/24"),
+	netip.MustParsePrefix("192.0.2.0/24"),
+	netip.MustParsePrefix("198.18.0.0/15"),
+	netip.MustParsePrefix("198.51.100.0/24"),
+	netip.MustParsePrefix("203.0.113.0/24"),
+	netip.MustParsePrefix("240.0.0.0/4"),
+	netip.MustParsePrefix("64:ff9b::/96"), // NAT64 can reach private IPv4
+	netip.MustParsePrefix("64:ff9b:1::/48"),
+	netip.MustParsePrefix("2001:db8::/32"),
+	netip.MustParsePrefix("2002::/16"), // 6to4 embeds an IPv4 address
+}
+
+// PublicAddr reports whether addr is a public unicast address. Loopback,
+// private, link-local (which includes cloud metadata endpoints), multicast
+// and reserved ranges are not.
+func PublicAddr(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	if !addr.IsGlobalUnicast() || addr.IsPrivate() || addr.IsLoopback() || addr.IsLinkLocalUnicast() {
+		return false
+	}
+	for _, p := range blockedPrefixes {
+		if p.Contains(addr) {
+			return false
+		}
+	}
+	return true
+}
+
+var (
+	metaPattern  = regexp.MustCompile(`(?is)<meta\s[^>]*>`)
+	attrPattern  = regexp.MustCompile(`(?is)([a-z:_-]+)\s*=\s*(?:"([^"]*)"|'([^']*)'|([^\s"'>]+))`)
+	titlePattern = regexp.MustCompile(`(?is)<title[^>]*>(.*?)</title>`)
+	spacePattern = regexp.MustCompile(`\s+`)
+)
+
+// Parse reads OpenGraph metadata from page, falling back to Twitter card
+// tags, the description meta tag and the title element. Relative image URLs
+// are resolved against base.
+func Parse(page string, base *url.URL) Preview {
+	meta := map[string]string{}
+	for _, tag := range metaPattern.FindAllString(page, -1) {
+		attrs := map[string]string{}
+		for _, m := range attrPattern.FindAllStringSubmatch(tag, -1) {
+			attrs[strings.ToLower(m[1])] = m[2] + m[3] + m[4]
+		}
+		key := strings.ToLower(attrs["property"])
+		if key == "" {
+			key = strings.ToLower(attrs["name"])
+		}
+		if _, seen := meta[key]; key != "" && !seen {
+			meta[key] = clean(attrs["content"])
+		}
+	}
+	first := func(keys ...string) string {
+		for _, k := range keys {
+			if v := meta[k]; v != "" {
+				return v
+			}
+		}
+		return ""
+	}
+
+	p := Preview{
+		Title:       first("og:title", "twitter:title"),
+		Description: first("og:description", "twitter:description", "description"),
+		SiteName:    first("og:site_name"),
+	}
+	if p.Title == "" {
+		if m := titlePattern.FindStringSubmatch(page); m != nil {
+			p.Title = clean(m[1])
+		}
+	}
+	p.Title = truncate(p.Title, maxTitle)
+	p.Description = truncate(p.Description, maxDescription)
+	p.SiteName = truncate(p.SiteName, maxTitle)
+
+	if image := first("og:image:secure_url", "og:image", "twitter:image"); image != "" {
+		if u, err := url.Parse(image); err == nil {
+			if base != nil {
+				u = base.ResolveReference(u)
+			}
+			if u.Scheme == "http" || u.Scheme == "https" {
+				p.ImageURL = u.String()
+			}
+		}
+	}
+	return p
+}
+
+func clean(s string) string {
+	return strings.TrimSpace(spacePattern.ReplaceAllString(html.UnescapeString(s), " "))
+}
+
+func truncate(s string, n int) string {
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	r := []rune(s)
+	return strings.TrimSpace(string(r[:n-1])) + "…"
+}
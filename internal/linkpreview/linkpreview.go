@@ -0,0 +1,95 @@
+// Package linkpreview finds links in chirps and fetches their OpenGraph
+// metadata in the background. Fetching goes through the Fetcher interface so
+// tests can stand in for the network, and the real HTTPFetcher refuses to
+// connect to private addresses so a chirp can't be used to probe the network
+// the server runs in.
+package linkpreview
+
+import (
+	"context"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// MaxLinks is how many links per chirp are turned into entities.
+const MaxLinks = 4
+
+// MaxURLLength is the longest link, in bytes, that is turned into an entity.
+// Preview URLs are a btree key, which can't hold much more than this.
+const MaxURLLength = 2048
+
+// Entity is a link found in a chirp. Start and End are offsets in runes
+// (Unicode code points) into the chirp body.
+type Entity struct {
+	URL        string
+	Start, End int
+}
+
+// Preview is the card shown for a link.
+type Preview struct {
+	URL         string `json:"url"`
+	Title       string `json:"title"`
+	Description string `json:"description,omitempty"`
+	ImageURL    string `json:"image_url,omitempty"`
+	SiteName    string `json:"site_name,omitempty"`
+}
+
+// Fetcher loads the preview for a URL.
+type Fetcher interface {
+	Fetch(ctx context.Context, url string) (Preview, error)
+}
+
+var linkPattern = regexp.MustCompile(`(?i)\bhttps?://[^\s<>"]+|\bwww\.[^\s<>"]+`)
+
+// Extract returns the links in body in order, at most MaxLinks of them, and
+// none longer than MaxURLLength.
+func Extract(body string) []Entity {
+	entities := Find(body)
+	if len(entities) > MaxLinks {
+		entities = entities[:MaxLinks]
+	}
+	return entities
+}
+
+// Find returns every link in body in order, none longer than MaxURLLength.
+// Links written without a scheme ("www.example.com") get https://, and
+// trailing punctuation that most likely ends the sentence is left out. It is
+// the one definition of a link, shared by chirp length and spam scoring.
+func Find(body string) []Entity {
+	var entities []Entity
+	for _, loc := range linkPattern.FindAllStringIndex(body, -1) {
+		raw := trimTrailing(body[loc[0]:loc[1]])
+		if !strings.Contains(raw, ".") || len(raw) > MaxURLLength {
+			continue
+		}
+		url := raw
+		if !strings.Contains(strings.ToLower(raw[:min(len(raw), 8)]), "://") {
+			url = "https://" + raw
+		}
+		start := utf8.RuneCountInString(body[:loc[0]])
+		entities = append(entities, Entity{
+			URL:   url,
+			Start: start,
+			End:   start + utf8.RuneCountInString(raw),
+		})
+	}
+	return entities
+}
+
+// trimTrailing drops sentence punctuation from the end of a link, and a
+// closing parenthesis unless the link itself opened one.
+func trimTrailing(link string) string {
+	for link != "" {
+		last := link[len(link)-1]
+		switch {
+		case strings.IndexByte(".,;:!?'*", last) >= 0:
+			link = link[:len(link)-1]
+		case last == ')' && strings.Count(link, "(") < strings.Count(link, ")"):
+			link = link[:len(link)-1]
+		default:
+			return link
+		}
+	}
+	return link
+}
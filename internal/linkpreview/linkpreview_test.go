@@ -0,0 +1,226 @@
+package linkpreview
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func allowAll(netip.Addr) bool { return true }
+
+func TestExtract(t *testing.T) {
+	got := Extract("read https://example.com/a?b=c, then (www.example.org/x). ünïcode http://go.dev")
+	want := []Entity{
+		{URL: "https://example.com/a?b=c", Start: 5, End: 30},
+		{URL: "https://www.example.org/x", Start: 38, End: 55},
+		{URL: "http://go.dev", Start: 66, End: 79},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %d entities, want %d: %+v", len(got), len(want), got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("entity %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+
+	if got := Extract("https://en.wikipedia.org/wiki/Go_(programming_language)"); got[0].URL != "https://en.wikipedia.org/wiki/Go_(programming_language)" {
+		t.Errorf("balanced parenthesis trimmed: %q", got[0].URL)
+	}
+	if got := Extract("no links, just http://localhost and www."); len(got) != 0 {
+		t.Errorf("expected no entities, got %+v", got)
+	}
+	if got := Extract("https://a.io/" + strings.Repeat("x", MaxURLLength)); len(got) != 0 {
+		t.Errorf("expected an oversized link to be skipped, got %d entities", len(got))
+	}
+	if got := Extract(strings.Repeat("https://a.io ", MaxLinks+2)); len(got) != MaxLinks {
+		t.Errorf("got %d entities, want %d", len(got), MaxLinks)
+	}
+}
+
+func TestPublicAddr(t *testing.T) {
+	tests := map[string]bool{
+		"93.184.216.34":      true,
+		"2606:2800:220:1::1": true,
+		"127.0.0.1":          false,
+		"10.1.2.3":           false,
+		"172.16.0.1":         false,
+		"192.168.1.1":        false,
+		"169.254.169.254":    false,
+		"100.64.0.1":         false,
+		"0.0.0.0":            false,
+		"::1":                false,
+		"fd00::1":            false,
+		"fe80::1":            false,
+		"::ffff:127.0.0.1":   false,
+		"64:ff9b::a00:1":     false,
+		"224.0.0.1":          false,
+		"255.255.255.255":    false,
+	}
+	for addr, want := range tests {
+		if got := PublicAddr(netip.MustParseAddr(addr)); got != want {
+			t.Errorf("PublicAddr(%s) = %v, want %v", addr, got, want)
+		}
+	}
+}
+
+const page = `<!doctype html><html><head>
+<title>Fallback title</title>
+<meta property="og:title" content="Chirpy &amp; friends">
+<meta name="description" content="plain description">
+<meta property="og:description" content="  A   place
+	to chirp  ">
+<meta property='og:image' content='/img/card.png'>
+<meta property="og:site_name" content="Chirpy">
+</head><body>hello</body></html>`
+
+func TestParse(t *testing.T) {
+	base, _ := url.Parse("https://chirpy.example/posts/1")
+	got := Parse(page, base)
+	want := Preview{
+		Title:       "Chirpy & friends",
+		Description: "A place to chirp",
+		ImageURL:    "https://chirpy.example/img/card.png",
+		SiteName:    "Chirpy",
+	}
+	if got != want {
+		t.Errorf("Parse() = %+v, want %+v", got, want)
+	}
+
+	got = Parse(`<html><head><title> Just a title </title></head></html>`, base)
+	if got.Title != "Just a title" || got.ImageURL != "" {
+		t.Errorf("title fallback: %+v", got)
+	}
+	got = Parse(`<meta property="og:image" content="javascript:alert(1)">`, base)
+	if got.ImageURL != "" {
+		t.Errorf("unsafe image url kept: %q", got.ImageURL)
+	}
+}
+
+func TestHTTPFetcher(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/page", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write([]byte(page))
+	})
+	mux.HandleFunc("/redirect", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/page", http.StatusFound)
+	})
+	mux.HandleFunc("/image", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/png")
+		w.Write([]byte("\x89PNG"))
+	})
+	mux.HandleFunc("/huge", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(strings.Repeat(" ", 4096) + page))
+	})
+	mux.HandleFunc("/slow", func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(2 * time.Second):
+		case <-r.Context().Done():
+		}
+	})
+	mux.HandleFunc("/missing", http.NotFound)
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	f := NewHTTPFetcher(Options{AllowAddr: allowAll, MaxBytes: 2048, Timeout: 200 * time.Millisecond})
+	ctx := context.Background()
+
+	p, err := f.Fetch(ctx, srv.URL+"/page")
+	if err != nil {
+		t.Fatalf("Fetch: %v", err)
+	}
+	if p.Title != "Chirpy & friends" || p.URL != srv.URL+"/page" || p.ImageURL != srv.URL+"/img/card.png" {
+		t.Errorf("unexpected preview %+v", p)
+	}
+	if p, err := f.Fetch(ctx, srv.URL+"/redirect"); err != nil || p.URL != srv.URL+"/redirect" {
+		t.Errorf("redirect: %+v, %v", p, err)
+	}
+	if _, err := f.Fetch(ctx, srv.URL+"/image"); !errors.Is(err, ErrNotHTML) {
+		t.Errorf("image: got %v, want ErrNotHTML", err)
+	}
+	if _, err := f.Fetch(ctx, srv.URL+"/huge"); !errors.Is(err, ErrNoMetadata) {
+		t.Errorf("page past the size limit: got %v, want ErrNoMetadata", err)
+	}
+	if _, err := f.Fetch(ctx, srv.URL+"/slow"); err == nil {
+		t.Error("slow page: expected a timeout")
+	}
+	if _, err := f.Fetch(ctx, srv.URL+"/missing"); err == nil {
+		t.Error("missing page: expected an error")
+	}
+	if _, err := f.Fetch(ctx, "file:///etc/passwd"); err == nil {
+		t.Error("file url: expected an error")
+	}
+}
+
+func TestHTTPFetcherBlocksPrivateAddresses(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("request reached a loopback server")
+	}))
+	defer srv.Close()
+
+	_, err := NewHTTPFetcher(Options{}).Fetch(context.Background(), srv.URL)
+	if !errors.Is(err, ErrBlockedAddress) {
+		t.Errorf("got %v, want ErrBlockedAddress", err)
+	}
+}
+
+type fakeFetcher map[string]Preview
+
+func (f fakeFetcher) Fetch(ctx context.Context, url string) (Preview, error) {
+	p, ok := f[url]
+	if !ok {
+		return Preview{}, errors.New("not found")
+	}
+	return p, nil
+}
+
+func TestWorkerRunOnce(t *testing.T) {
+	store := NewMemoryStore()
+	store.Add("https://a.example")
+	store.Add("https://b.example")
+	store.Add("https://a.example")
+
+	w := NewWorker(fakeFetcher{"https://a.example": {Title: "A"}}, store)
+	n, err := w.RunOnce(context.Background())
+	if err != nil || n != 2 {
+		t.Fatalf("RunOnce() = %d, %v, want 2, nil", n, err)
+	}
+	if p, ok := store.Preview("https://a.example"); !ok || p.Title != "A" || p.URL != "https://a.example" {
+		t.Errorf("preview for a = %+v, %v", p, ok)
+	}
+	if _, ok := store.Failure("https://b.example"); !ok {
+		t.Error("b should have failed")
+	}
+	if n, _ := w.RunOnce(context.Background()); n != 0 {
+		t.Errorf("second run processed %d links, want 0", n)
+	}
+}
+
+func TestWorkerNotify(t *testing.T) {
+	store := NewMemoryStore()
+	w := NewWorker(fakeFetcher{"https://a.example": {Title: "A"}}, store)
+	w.Interval = time.Hour
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go w.Run(ctx)
+
+	store.Add("https://a.example")
+	w.Notify()
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		if _, ok := store.Preview("https://a.example"); ok {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal("worker did not pick up the link after Notify")
+}
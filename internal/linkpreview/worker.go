@@ -0,0 +1,226 @@
+package linkpreview
+
+import (
+	"context"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/anton-jj/chripy/internal/database"
+)
+
+// Store keeps the queue of links waiting for a preview and the results.
+type Store interface {
+	Pending(ctx context.Context, limit int) ([]string, error)
+	Complete(ctx context.Context, p Preview) error
+	Fail(ctx context.Context, url, reason string) error
+}
+
+const (
+	DefaultInterval    = time.Minute
+	DefaultBatchSize   = 20
+	DefaultConcurrency = 4
+)
+
+// Worker fetches previews for pending links. It polls the store every
+// Interval and straight away when Notify is called. A link that fails is
+// marked failed and not tried again.
+type Worker struct {
+	fetcher     Fetcher
+	store       Store
+	Interval    time.Duration
+	BatchSize   int
+	Concurrency int
+
+	wake chan struct{}
+}
+
+func NewWorker(fetcher Fetcher, store Store) *Worker {
+	return &Worker{
+		fetcher:     fetcher,
+		store:       store,
+		Interval:    DefaultInterval,
+		BatchSize:   DefaultBatchSize,
+		Concurrency: DefaultConcurrency,
+		wake:        make(chan struct{}, 1),
+	}
+}
+
+// Notify tells the worker there is new work. It never blocks.
+func (w *Worker) Notify() {
+	select {
+	case w.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Run processes batches until ctx is done.
+func (w *Worker) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.Interval)
+	defer ticker.Stop()
+	for {
+		n, err := w.RunOnce(ctx)
+		if err != nil {
+			log.Printf("linkpreview: %v", err)
+		}
+		if err == nil && n == w.BatchSize {
+			continue
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-w.wake:
+		}
+	}
+}
+
+// RunOnce fetches one batch of pending links and reports how many of them it
+// finished, successfully or not.
+func (w *Worker) RunOnce(ctx context.Context) (int, error) {
+	urls, err := w.store.Pending(ctx, w.BatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	sem := make(chan struct{}, max(w.Concurrency, 1))
+	var wg sync.WaitGroup
+	var done atomic.Int32
+	for _, u := range urls {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			if w.process(ctx, u) {
+				done.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+	return int(done.Load()), nil
+}
+
+func (w *Worker) process(ctx context.Context, url string) bool {
+	p, err := w.fetcher.Fetch(ctx, url)
+	if err != nil {
+		err = w.store.Fail(ctx, url, err.Error())
+	} else {
+		p.URL = url
+		err = w.store.Complete(ctx, p)
+	}
+	if err != nil {
+		log.Printf("linkpreview: failed to save %s: %v", url, err)
+		return false
+	}
+	return true
+}
+
+// MemoryStore keeps the queue in memory, for tests.
+type MemoryStore struct {
+	mu       sync.Mutex
+	pending  []string
+	previews map[string]Preview
+	failures map[string]string
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{previews: map[string]Preview{}, failures: map[string]string{}}
+}
+
+// Add queues url unless it is already known.
+func (s *MemoryStore) Add(url string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, p := range s.pending {
+		if p == url {
+			return
+		}
+	}
+	if _, ok := s.previews[url]; ok {
+		return
+	}
+	if _, ok := s.failures[url]; ok {
+		return
+	}
+	s.pending = append(s.pending, url)
+}
+
+func (s *MemoryStore) Pending(ctx context.Context, limit int) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n := min(limit, len(s.pending))
+	return append([]string(nil), s.pending[:n]...), nil
+}
+
+func (s *MemoryStore) Complete(ctx context.Context, p Preview) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.remove(p.URL)
+	s.previews[p.URL] = p
+	return nil
+}
+
+func (s *MemoryStore) Fail(ctx context.Context, url, reason string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.remove(url)
+	s.failures[url] = reason
+	return nil
+}
+
+// Preview returns the stored preview for url, if it was fetched.
+func (s *MemoryStore) Preview(url string) (Preview, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	p, ok := s.previews[url]
+	return p, ok
+}
+
+// Failure returns why fetching url failed, if it did.
+func (s *MemoryStore) Failure(url string) (string, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	reason, ok := s.failures[url]
+	return reason, ok
+}
+
+func (s *MemoryStore) remove(url string) {
+	for i, p := range s.pending {
+		if p == url {
+			s.pending = append(s.pending[:i], s.pending[i+1:]...)
+			return
+		}
+	}
+}
+
+// PostgresStore keeps the queue in the link_previews table.
+type PostgresStore struct {
+	db *database.Queries
+}
+
+func NewPostgresStore(db *database.Queries) *PostgresStore {
+	return &PostgresStore{db: db}
+}
+
+func (s *PostgresStore) Pending(ctx context.Context, limit int) ([]string, error) {
+	return s.db.ListPendingLinkPreviews(ctx, int32(limit))
+}
+
+func (s *PostgresStore) Complete(ctx context.Context, p Preview) error {
+	return s.db.CompleteLinkPreview(ctx, database.CompleteLinkPreviewParams{
+		Title:       p.Title,
+		Description: p.Description,
+		ImageUrl:    p.ImageURL,
+		SiteName:    p.SiteName,
+		Url:         p.URL,
+	})
+}
+
+func (s *PostgresStore) Fail(ctx context.Context, url, reason string) error {
+	return s.db.FailLinkPreview(ctx, database.FailLinkPreviewParams{
+		Error: reason,
+		Url:   url,
+	})
+}
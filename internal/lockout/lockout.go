@@ -0,0 +1,212 @@
+// Package lockout throttles repeated failures (such as wrong passwords) per
+// key with exponential backoff and temporary lockouts.
+package lockout
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/anton-jj/chripy/internal/database"
+)
+
+// Record is the failure history kept for one key.
+type Record struct {
+	Failures    int
+	LastFailure time.Time
+	LockedUntil time.Time
+}
+
+// Store persists Records. RecordFailure must start counting from one again when
+// the previous failure happened before windowStart.
+type Store interface {
+	Get(ctx context.Context, key string) (Record, error)
+	RecordFailure(ctx context.Context, key string, now, windowStart time.Time) (Record, error)
+	Lock(ctx context.Context, key string, until time.Time) error
+	Reset(ctx context.Context, key string) error
+}
+
+type Policy struct {
+	// FreeAttempts failures are allowed back to back before backoff starts.
+	FreeAttempts int
+	// BaseDelay doubles with every failure past FreeAttempts, up to MaxDelay.
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+	// After LockoutThreshold failures the key is locked for LockoutDuration.
+	LockoutThreshold int
+	LockoutDuration  time.Duration
+	// Failures older than Window are forgotten.
+	Window time.Duration
+}
+
+type Limiter struct {
+	store  Store
+	policy Policy
+}
+
+func New(store Store, policy Policy) *Limiter {
+	return &Limiter{store: store, policy: policy}
+}
+
+// Check reports how long the caller has to wait before key may try again; zero
+// means go ahead.
+func (l *Limiter) Check(ctx context.Context, key string, now time.Time) (time.Duration, error) {
+	rec, err := l.store.Get(ctx, key)
+	if err != nil {
+		return 0, err
+	}
+	if now.Sub(rec.LastFailure) > l.policy.Window {
+		return 0, nil
+	}
+	if rec.LockedUntil.After(now) {
+		return rec.LockedUntil.Sub(now), nil
+	}
+	if wait := rec.LastFailure.Add(l.delay(rec.Failures)).Sub(now); wait > 0 {
+		return wait, nil
+	}
+	return 0, nil
+}
+
+// Fail records a failure for key and reports whether it just caused a lockout.
+// Every failure from LockoutThreshold on locks the key again, so one that
+// keeps failing after a lockout expires is locked straight away.
+func (l *Limiter) Fail(ctx context.Context, key string, now time.Time) (bool, error) {
+	rec, err := l.store.RecordFailure(ctx, key, now, now.Add(-l.policy.Window))
+	if err != nil {
+		return false, err
+	}
+	if l.policy.LockoutThreshold > 0 && rec.Failures >= l.policy.LockoutThreshold {
+		return true, l.store.Lock(ctx, key, now.Add(l.policy.LockoutDuration))
+	}
+	return false, nil
+}
+
+func (l *Limiter) Succeed(ctx context.Context, key string) error {
+	return l.store.Reset(ctx, key)
+}
+
+func (l *Limiter) delay(failures int) time.Duration {
+	over := failures - l.policy.FreeAttempts
+	if over <= 0 {
+		return 0
+	}
+	d := l.policy.BaseDelay
+	for i := 1; i < over && d < l.policy.MaxDelay; i++ {
+		d *= 2
+	}
+	return min(d, l.policy.MaxDelay)
+}
+
+// MemoryStore keeps records in process memory. It is fine for a single
+// instance; use PostgresStore when running several. Records that fall out of
+// the window are swept away about once per window, so keys that are never
+// tried again don't pile up.
+type MemoryStore struct {
+	mu        sync.Mutex
+	records   map[string]Record
+	lastSweep time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{records: map[string]Record{}}
+}
+
+func (s *MemoryStore) Get(ctx context.Context, key string) (Record, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.records[key], nil
+}
+
+func (s *MemoryStore) RecordFailure(ctx context.Context, key string, now, windowStart time.Time) (Record, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.lastSweep.Before(windowStart) {
+		for k, r := range s.records {
+			if r.LastFailure.Before(windowStart) {
+				delete(s.records, k)
+			}
+		}
+		s.lastSweep = now
+	}
+	rec := s.records[key]
+	if rec.LastFailure.Before(windowStart) {
+		rec = Record{}
+	}
+	rec.Failures++
+	rec.LastFailure = now
+	s.records[key] = rec
+	return rec, nil
+}
+
+func (s *MemoryStore) Lock(ctx context.Context, key string, until time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	rec := s.records[key]
+	rec.LockedUntil = until
+	s.records[key] = rec
+	return nil
+}
+
+func (s *MemoryStore) Reset(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.records, key)
+	return nil
+}
+
+// PostgresStore shares records between instances through the login_attempts
+// table.
+type PostgresStore struct {
+	db *database.Queries
+}
+
+func NewPostgresStore(db *database.Queries) *PostgresStore {
+	return &PostgresStore{db: db}
+}
+
+func (s *PostgresStore) Get(ctx context.Context, key string) (Record, error) {
+	row, err := s.db.GetLoginAttempts(ctx, key)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Record{}, nil
+	}
+	if err != nil {
+		return Record{}, err
+	}
+	return toRecord(row), nil
+}
+
+func (s *PostgresStore) RecordFailure(ctx context.Context, key string, now, windowStart time.Time) (Record, error) {
+	row, err := s.db.RecordLoginFailure(ctx, database.RecordLoginFailureParams{
+		AttemptKey:  key,
+		FailedAt:    now.UTC(),
+		WindowStart: windowStart.UTC(),
+	})
+	if err != nil {
+		return Record{}, err
+	}
+	return toRecord(row), nil
+}
+
+func (s *PostgresStore) Lock(ctx context.Context, key string, until time.Time) error {
+	return s.db.LockLoginAttempts(ctx, database.LockLoginAttemptsParams{
+		LockedUntil: sql.NullTime{Valid: true, Time: until.UTC()},
+		AttemptKey:  key,
+	})
+}
+
+func (s *PostgresStore) Reset(ctx context.Context, key string) error {
+	return s.db.ResetLoginAttempts(ctx, key)
+}
+
+func toRecord(row database.LoginAttempt) Record {
+	rec := Record{
+		Failures:    int(row.Failures),
+		LastFailure: row.LastFailureAt,
+	}
+	if row.LockedUntil.Valid {
+		rec.LockedUntil = row.LockedUntil.Time
+	}
+	return rec
+}
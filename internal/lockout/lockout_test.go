@@ -0,0 +1,107 @@
+package lockout
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestLimiter(t *testing.T) {
+	ctx := context.Background()
+	l := New(NewMemoryStore(), Policy{
+		FreeAttempts:     3,
+		BaseDelay:        time.Second,
+		MaxDelay:         4 * time.Second,
+		LockoutThreshold: 6,
+		LockoutDuration:  time.Hour,
+		Window:           24 * time.Hour,
+	})
+	now := time.Unix(1700000000, 0)
+	key := "account:a@example.com"
+
+	for i := 0; i < 3; i++ {
+		if wait, _ := l.Check(ctx, key, now); wait != 0 {
+			t.Fatalf("attempt %d: expected no wait, got %v", i+1, wait)
+		}
+		l.Fail(ctx, key, now)
+	}
+
+	// Three free failures used up: the 4th failure starts the backoff.
+	if wait, _ := l.Check(ctx, key, now); wait != 0 {
+		t.Errorf("expected no wait after free attempts, got %v", wait)
+	}
+	wantDelays := []time.Duration{time.Second, 2 * time.Second}
+	for _, want := range wantDelays {
+		l.Fail(ctx, key, now)
+		if wait, _ := l.Check(ctx, key, now); wait != want {
+			t.Errorf("expected wait %v, got %v", want, wait)
+		}
+		now = now.Add(want)
+	}
+
+	locked, err := l.Fail(ctx, key, now)
+	if err != nil || !locked {
+		t.Fatalf("expected the 6th failure to lock, got %v %v", locked, err)
+	}
+	if wait, _ := l.Check(ctx, key, now.Add(time.Minute)); wait != time.Hour-time.Minute {
+		t.Errorf("expected lockout to remain, got %v", wait)
+	}
+	if wait, _ := l.Check(ctx, key, now.Add(time.Hour)); wait != 0 {
+		t.Errorf("expected lockout to expire, got %v", wait)
+	}
+
+	// Failing again within the window once the lockout is over locks again.
+	now = now.Add(time.Hour)
+	locked, err = l.Fail(ctx, key, now)
+	if err != nil || !locked {
+		t.Fatalf("expected the 7th failure to lock again, got %v %v", locked, err)
+	}
+	if wait, _ := l.Check(ctx, key, now); wait != time.Hour {
+		t.Errorf("expected a fresh lockout, got %v", wait)
+	}
+
+	if err := l.Succeed(ctx, key); err != nil {
+		t.Fatalf("Succeed() had an error %v", err)
+	}
+	if wait, _ := l.Check(ctx, key, now); wait != 0 {
+		t.Errorf("expected reset after success, got %v", wait)
+	}
+}
+
+func TestLimiterWindow(t *testing.T) {
+	ctx := context.Background()
+	l := New(NewMemoryStore(), Policy{
+		FreeAttempts: 1,
+		BaseDelay:    time.Minute,
+		MaxDelay:     time.Hour,
+		Window:       time.Hour,
+	})
+	now := time.Unix(1700000000, 0)
+	l.Fail(ctx, "k", now)
+	l.Fail(ctx, "k", now)
+	if wait, _ := l.Check(ctx, "k", now); wait != time.Minute {
+		t.Fatalf("expected a minute of backoff, got %v", wait)
+	}
+
+	later := now.Add(2 * time.Hour)
+	l.Fail(ctx, "k", later)
+	if wait, _ := l.Check(ctx, "k", later); wait != 0 {
+		t.Errorf("expected old failures to be forgotten, got %v", wait)
+	}
+}
+
+func TestMemoryStoreSweep(t *testing.T) {
+	ctx := context.Background()
+	s := NewMemoryStore()
+	l := New(s, Policy{Window: time.Hour})
+	now := time.Unix(1700000000, 0)
+	for _, key := range []string{"a", "b", "c"} {
+		l.Fail(ctx, key, now)
+	}
+
+	later := now.Add(2 * time.Hour)
+	l.Fail(ctx, "d", later)
+	if len(s.records) != 1 {
+		t.Errorf("expected expired records to be swept, have %d", len(s.records))
+	}
+}
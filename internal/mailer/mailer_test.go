@@ -0,0 +1,44 @@
+package mailer
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestMemoryMailer(t *testing.T) {
+	m := &MemoryMailer{}
+	msg := Message{To: "a@example.com", Subject: "hi", Body: "hello"}
+	if err := m.Send(context.Background(), msg); err != nil {
+		t.Fatalf("Send() had an error %v", err)
+	}
+	sent := m.Sent()
+	if len(sent) != 1 || sent[0] != msg {
+		t.Errorf("expected %v, got %v", msg, sent)
+	}
+}
+
+func TestFileMailer(t *testing.T) {
+	dir := t.TempDir()
+	m := &FileMailer{Dir: dir, From: "chirpy@example.com"}
+	err := m.Send(context.Background(), Message{To: "a/../b@example.com", Subject: "Verify", Body: "line1\nline2"})
+	if err != nil {
+		t.Fatalf("Send() had an error %v", err)
+	}
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.eml"))
+	if err != nil || len(files) != 1 {
+		t.Fatalf("expected one .eml file, got %v (%v)", files, err)
+	}
+	data, err := os.ReadFile(files[0])
+	if err != nil {
+		t.Fatalf("ReadFile failed %v", err)
+	}
+	for _, want := range []string{"From: chirpy@example.com\r\n", "Subject: Verify\r\n", "line1\r\nline2"} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("expected message to contain %q", want)
+		}
+	}
+}
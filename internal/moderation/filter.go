@@ -0,0 +1,60 @@
+package moderation
+
+import "strings"
+
+// Phrase is a keyword filter phrase, normalized once so it can be looked for
+// in many texts.
+type Phrase struct {
+	wholeWord bool
+	words     []string
+	needle    string
+}
+
+// NewPhrase normalizes phrase the same way as rules. With wholeWord the phrase
+// has to line up with word boundaries, otherwise it may also appear inside a
+// longer word ("cat" in "concatenate").
+func NewPhrase(phrase string, wholeWord bool) Phrase {
+	p := Phrase{wholeWord: wholeWord}
+	if !wholeWord {
+		p.needle = Normalize(phrase)
+		return p
+	}
+	for _, t := range tokenize(phrase) {
+		p.words = append(p.words, t.text)
+	}
+	return p
+}
+
+// Text is a text normalized and split into words once, so many phrases can be
+// looked for in it.
+type Text struct {
+	tokens     []token
+	normalized string
+}
+
+func NewText(text string) Text {
+	tokens := tokenize(text)
+	return Text{tokens: tokens, normalized: joinTokens(tokens)}
+}
+
+// Contains reports whether the text mentions p.
+func (t Text) Contains(p Phrase) bool {
+	if !p.wholeWord {
+		return p.needle != "" && strings.Contains(t.normalized, p.needle)
+	}
+	if len(p.words) == 0 {
+		return false
+	}
+	for i := 0; i+len(p.words) <= len(t.tokens); i++ {
+		if matchAt(t.tokens[i:], p.words) {
+			return true
+		}
+	}
+	return false
+}
+
+// Contains reports whether text mentions phrase. It is NewText and NewPhrase
+// for a single check; use those directly when checking many.
+func Contains(text, phrase string, wholeWord bool) bool {
+	return NewText(text).Contains(NewPhrase(phrase, wholeWord))
+}
@@ -0,0 +1,177 @@
+// Package moderation checks user content against configurable word lists.
+// Text is normalized before matching so punctuation, look-alike characters,
+// accents and leetspeak don't get around a rule.
+package moderation
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+type Action string
+
+const (
+	// ActionMask replaces the matched words with asterisks.
+	ActionMask Action = "mask"
+	// ActionFlag lets the content through but marks it for review.
+	ActionFlag Action = "flag"
+	// ActionReject refuses the content.
+	ActionReject Action = "reject"
+)
+
+var ErrInvalidRule = errors.New("moderation: a rule needs a term and one of the actions mask, flag or reject")
+
+func (a Action) Valid() bool {
+	return a == ActionMask || a == ActionFlag || a == ActionReject
+}
+
+// Rule matches Term, a word or phrase, wherever it appears as whole words.
+type Rule struct {
+	Term   string `json:"term"`
+	Action Action `json:"action"`
+}
+
+func (r Rule) Validate() error {
+	if Normalize(r.Term) == "" || !r.Action.Valid() {
+		return ErrInvalidRule
+	}
+	return nil
+}
+
+type Match struct {
+	Term   string
+	Action Action
+}
+
+// Result is the outcome of checking a text. Body is the text with every
+// masked term replaced.
+type Result struct {
+	Body     string
+	Rejected bool
+	Flagged  bool
+	Matches  []Match
+}
+
+// Source provides the current rules. Sources are read again on every reload.
+type Source interface {
+	Rules(ctx context.Context) ([]Rule, error)
+}
+
+type compiledRule struct {
+	rule  Rule
+	words []string
+}
+
+// Engine holds the active rules. It is safe for concurrent use, and Reload
+// swaps the rules without blocking Check.
+type Engine struct {
+	sources []Source
+	rules   atomic.Pointer[[]compiledRule]
+}
+
+func New(sources ...Source) *Engine {
+	e := &Engine{sources: sources}
+	e.rules.Store(&[]compiledRule{})
+	return e
+}
+
+// Reload reads every source and, if all of them succeed, replaces the active
+// rules. On error the previous rules stay in place.
+func (e *Engine) Reload(ctx context.Context) error {
+	var compiled []compiledRule
+	for _, s := range e.sources {
+		rules, err := s.Rules(ctx)
+		if err != nil {
+			return err
+		}
+		for _, r := range rules {
+			if err := r.Validate(); err != nil {
+				log.Printf("moderation: skipping rule %q: %v", r.Term, err)
+				continue
+			}
+			var words []string
+			for _, t := range tokenize(r.Term) {
+				words = append(words, t.text)
+			}
+			compiled = append(compiled, compiledRule{rule: r, words: words})
+		}
+	}
+	e.rules.Store(&compiled)
+	return nil
+}
+
+// Watch reloads the rules every interval until ctx is done, so edits to a
+// rules file or to another instance's rules are picked up.
+func (e *Engine) Watch(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := e.Reload(ctx); err != nil {
+				log.Printf("moderation: reload failed, keeping the current rules: %v", err)
+			}
+		}
+	}
+}
+
+func (e *Engine) Check(text string) Result {
+	res := Result{Body: text}
+	tokens := tokenize(text)
+	rules := *e.rules.Load()
+
+	type span struct{ start, end int }
+	var masks []span
+	for _, cr := range rules {
+		for i := 0; i+len(cr.words) <= len(tokens); i++ {
+			if !matchAt(tokens[i:], cr.words) {
+				continue
+			}
+			res.Matches = append(res.Matches, Match{Term: cr.rule.Term, Action: cr.rule.Action})
+			switch cr.rule.Action {
+			case ActionReject:
+				res.Rejected = true
+			case ActionFlag:
+				res.Flagged = true
+			case ActionMask:
+				masks = append(masks, span{tokens[i].start, tokens[i+len(cr.words)-1].end})
+			}
+		}
+	}
+	if len(masks) == 0 {
+		return res
+	}
+
+	sort.Slice(masks, func(i, j int) bool { return masks[i].start < masks[j].start })
+	var b strings.Builder
+	last := 0
+	for _, m := range masks {
+		if m.end <= last {
+			continue
+		}
+		if m.start >= last {
+			b.WriteString(text[last:m.start])
+			b.WriteString("****")
+		}
+		last = m.end
+	}
+	b.WriteString(text[last:])
+	res.Body = b.String()
+	return res
+}
+
+func matchAt(tokens []token, words []string) bool {
+	for j, w := range words {
+		if !tokens[j].matches(w) {
+			return false
+		}
+	}
+	return true
+}
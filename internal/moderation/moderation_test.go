@@ -0,0 +1,134 @@
+package moderation
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func newEngine(t *testing.T, rules ...Rule) *Engine {
+	t.Helper()
+	e := New(StaticSource(rules))
+	if err := e.Reload(context.Background()); err != nil {
+		t.Fatalf("Reload failed %v", err)
+	}
+	return e
+}
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{in: "Kerfuffle!", want: "kerfuffle"},
+		{in: "kеrfuffle", want: "kerfuffle"}, // Cyrillic е
+		{in: "ＫＥＲＦＵＦＦＬＥ", want: "kerfuffle"},
+		{in: "kérfüffle", want: "kerfuffle"},
+		{in: "kérfuffle", want: "kerfuffle"},
+		{in: "ker​fuffle", want: "kerfuffle"},
+		{in: "k3rfuffl3", want: "kerfuffle"},
+		{in: "sh@rbert", want: "sharbert"},
+		{in: "𝐟𝐨𝐫𝐧𝐚𝐱", want: "fornax"},
+		{in: "in 2024, really!", want: "in 2024 really"},
+	}
+	for _, tt := range tests {
+		if got := Normalize(tt.in); got != tt.want {
+			t.Errorf("Normalize(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestEngineCheck(t *testing.T) {
+	e := newEngine(t,
+		Rule{Term: "kerfuffle", Action: ActionMask},
+		Rule{Term: "buy followers", Action: ActionReject},
+		Rule{Term: "fornax", Action: ActionFlag},
+	)
+
+	tests := []struct {
+		name         string
+		body         string
+		wantBody     string
+		wantRejected bool
+		wantFlagged  bool
+	}{
+		{name: "clean", body: "What a day", wantBody: "What a day"},
+		{name: "masked with punctuation", body: "What a kerfuffle!", wantBody: "What a ****!"},
+		{name: "masked look-alike", body: "Such a kеrfuff1e, honestly", wantBody: "Such a ****, honestly"},
+		{name: "part of another word", body: "kerfuffles happen", wantBody: "kerfuffles happen"},
+		{name: "phrase rejected", body: "Buy   FOLLOWERS today", wantBody: "Buy   FOLLOWERS today", wantRejected: true},
+		{name: "flagged", body: "ask fornax", wantBody: "ask fornax", wantFlagged: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := e.Check(tt.body)
+			if res.Body != tt.wantBody || res.Rejected != tt.wantRejected || res.Flagged != tt.wantFlagged {
+				t.Errorf("Check(%q) = %+v", tt.body, res)
+			}
+		})
+	}
+}
+
+type brokenSource struct{}
+
+func (brokenSource) Rules(ctx context.Context) ([]Rule, error) {
+	return nil, errors.New("database is down")
+}
+
+func TestReloadFromFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rules.json")
+	write := func(content string) {
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write(`[{"term": "sharbert", "action": "mask"}]`)
+
+	e := New(FileSource{Path: path})
+	if err := e.Reload(context.Background()); err != nil {
+		t.Fatalf("Reload failed %v", err)
+	}
+	if got := e.Check("sharbert").Body; got != "****" {
+		t.Errorf("expected sharbert to be masked, got %q", got)
+	}
+
+	write(`[{"term": "sharbert", "action": "reject"}, {"term": "", "action": "mask"}]`)
+	if err := e.Reload(context.Background()); err != nil {
+		t.Fatalf("Reload failed %v", err)
+	}
+	if !e.Check("sharbert").Rejected {
+		t.Errorf("expected the edited rule to apply after reload")
+	}
+
+	broken := New(FileSource{Path: path}, brokenSource{})
+	if err := broken.Reload(context.Background()); err == nil {
+		t.Errorf("expected an error from a failing source")
+	}
+	if broken.Check("sharbert").Rejected {
+		t.Errorf("expected no rules after a failed first load")
+	}
+}
+
+func TestContains(t *testing.T) {
+	tests := []struct {
+		text, phrase string
+		wholeWord    bool
+		want         bool
+	}{
+		{"I love my cat", "cat", true, true},
+		{"stop concatenating strings", "cat", true, false},
+		{"stop concatenating strings", "cat", false, true},
+		{"Spoilers for the F1NALE ahead", "finale", true, true},
+		{"the season finale was great", "season finale", true, true},
+		{"the season, finale", "season finale", true, true},
+		{"the finale season", "season finale", true, false},
+		{"anything", "  ", false, false},
+	}
+	for _, tt := range tests {
+		if got := Contains(tt.text, tt.phrase, tt.wholeWord); got != tt.want {
+			t.Errorf("Contains(%q, %q, %v) = %v, want %v", tt.text, tt.phrase, tt.wholeWord, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,236 @@
+package moderation
+
+import (
+	"strings"
+	"unicode"
+)
+
+// token is one word of a text after normalization, with the byte range it
+// came from so a match can be masked in the original.
+type token struct {
+	text       string
+	leet       bool
+	start, end int
+}
+
+// matches reports whether the token spells word, reading digits and symbols
+// as letters when the token is a leetspeak word.
+func (t token) matches(word string) bool {
+	tr, wr := []rune(t.text), []rune(word)
+	if len(tr) != len(wr) {
+		return false
+	}
+	for i, r := range tr {
+		if r == wr[i] {
+			continue
+		}
+		if !t.leet || !strings.ContainsRune(leet[r], wr[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// confusables maps characters that render like a Latin letter, or are a Latin
+// letter with a diacritic, to that letter. Keys are lower case because text is
+// lowered before the lookup.
+var confusables = func() map[rune]rune {
+	m := map[rune]rune{}
+	add := func(chars string, to rune) {
+		for _, r := range chars {
+			m[r] = to
+		}
+	}
+	// Latin with diacritics.
+	add("àáâãäåāăąǎ", 'a')
+	add("çćĉċč", 'c')
+	add("ďđ", 'd')
+	add("èéêëēĕėęě", 'e')
+	add("ĝğġģ", 'g')
+	add("ĥħ", 'h')
+	add("ìíîïĩīĭįıǐ", 'i')
+	add("ĵ", 'j')
+	add("ķ", 'k')
+	add("ĺļľŀł", 'l')
+	add("ñńņňŉ", 'n')
+	add("òóôõöøōŏőǒ", 'o')
+	add("ŕŗř", 'r')
+	add("śŝşšș", 's')
+	add("ţťŧț", 't')
+	add("ùúûüũūŭůűųǔ", 'u')
+	add("ŵ", 'w')
+	add("ýÿŷ", 'y')
+	add("źżž", 'z')
+	// Cyrillic.
+	add("а", 'a')
+	add("в", 'b')
+	add("с", 'c')
+	add("ԁ", 'd')
+	add("её", 'e')
+	add("һн", 'h')
+	add("іїӏ", 'i')
+	add("ј", 'j')
+	add("к", 'k')
+	add("м", 'm')
+	add("о", 'o')
+	add("р", 'p')
+	add("ԛ", 'q')
+	add("ѕ", 's')
+	add("т", 't')
+	add("ԝ", 'w')
+	add("х", 'x')
+	add("у", 'y')
+	// Greek.
+	add("α", 'a')
+	add("β", 'b')
+	add("ϲ", 'c')
+	add("ε", 'e')
+	add("η", 'n')
+	add("ι", 'i')
+	add("κ", 'k')
+	add("μ", 'm')
+	add("ν", 'v')
+	add("ο", 'o')
+	add("ρ", 'p')
+	add("τ", 't')
+	add("υ", 'u')
+	add("χ", 'x')
+	add("ζ", 'z')
+	return m
+}()
+
+// leet lists the letters a digit or symbol may stand for. It only applies
+// inside words that also contain letters, so "2024" stays a number.
+var leet = map[rune]string{
+	'0': "o",
+	'1': "il",
+	'3': "e",
+	'4': "a",
+	'5': "s",
+	'7': "t",
+	'8': "b",
+	'@': "a",
+	'$': "s",
+	'!': "il",
+	'|': "li",
+}
+
+func isInvisible(r rune) bool {
+	switch r {
+	case '\u00ad', '\u200b', '\u200c', '\u200d', '\u2060', '\ufeff':
+		return true
+	}
+	return unicode.Is(unicode.Mn, r)
+}
+
+func isWordRune(r rune) bool {
+	_, isLeet := leet[r]
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || isLeet || isInvisible(r) || isMathAlnum(r)
+}
+
+func isMathAlnum(r rune) bool {
+	return r >= 0x1d400 && r <= 0x1d7ff
+}
+
+// foldRune undoes width, style and look-alike tricks for a single character.
+// It returns -1 for characters that should disappear.
+func foldRune(r rune) rune {
+	if isInvisible(r) {
+		return -1
+	}
+	// Fullwidth forms.
+	if r >= 0xff01 && r <= 0xff5e {
+		r -= 0xfee0
+	}
+	// Mathematical bold, italic, script, ... letters come in runs of 52.
+	if r >= 0x1d400 && r <= 0x1d6a3 {
+		i := (r - 0x1d400) % 52
+		if i < 26 {
+			r = 'a' + i
+		} else {
+			r = 'a' + i - 26
+		}
+	}
+	// Mathematical digits come in runs of 10.
+	if r >= 0x1d7ce && r <= 0x1d7ff {
+		r = '0' + (r-0x1d7ce)%10
+	}
+	r = unicode.ToLower(r)
+	if to, ok := confusables[r]; ok {
+		return to
+	}
+	return r
+}
+
+// tokenize splits text into normalized words. Punctuation and whitespace
+// separate words; "!" and "|" at the edges of a word are punctuation, inside
+// it they may stand for a letter.
+func tokenize(text string) []token {
+	var tokens []token
+	start := -1
+	flush := func(end int) {
+		if start < 0 {
+			return
+		}
+		word := text[start:end]
+		trimmedStart := start + (len(word) - len(strings.TrimLeft(word, "!|")))
+		word = strings.Trim(word, "!|")
+		start = -1
+		if word == "" {
+			return
+		}
+		if t, hasLetter := foldWord(word); t != "" {
+			tokens = append(tokens, token{text: t, leet: hasLetter, start: trimmedStart, end: trimmedStart + len(word)})
+		}
+	}
+	for i, r := range text {
+		if isWordRune(r) {
+			if start < 0 {
+				start = i
+			}
+			continue
+		}
+		flush(i)
+	}
+	flush(len(text))
+	return tokens
+}
+
+func foldWord(word string) (string, bool) {
+	folded := make([]rune, 0, len(word))
+	hasLetter := false
+	for _, r := range word {
+		r = foldRune(r)
+		if r < 0 {
+			continue
+		}
+		if unicode.IsLetter(r) {
+			hasLetter = true
+		}
+		folded = append(folded, r)
+	}
+	return string(folded), hasLetter
+}
+
+// Normalize returns the words of text with look-alikes folded and leetspeak
+// read as its most likely letters, joined by single spaces.
+func Normalize(text string) string {
+	return joinTokens(tokenize(text))
+}
+
+// joinTokens is Normalize for text that has already been tokenized.
+func joinTokens(tokens []token) string {
+	words := make([]string, len(tokens))
+	for i, t := range tokens {
+		word := []rune(t.text)
+		if t.leet {
+			for j, r := range word {
+				if to, ok := leet[r]; ok {
+					word[j] = []rune(to)[0]
+				}
+			}
+		}
+		words[i] = string(word)
+	}
+	return strings.Join(words, " ")
+}
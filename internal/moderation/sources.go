@@ -0,0 +1,59 @@
+package moderation
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+
+	"github.com/anton-jj/chripy/internal/database"
+)
+
+// StaticSource is a fixed rule list.
+type StaticSource []Rule
+
+func (s StaticSource) Rules(ctx context.Context) ([]Rule, error) {
+	return s, nil
+}
+
+// FileSource reads a JSON array of rules, for example
+//
+//	[{"term": "kerfuffle", "action": "mask"}]
+//
+// The file is read again on every reload, so editing it is enough to change
+// the rules of a running server.
+type FileSource struct {
+	Path string
+}
+
+func (s FileSource) Rules(ctx context.Context) ([]Rule, error) {
+	data, err := os.ReadFile(s.Path)
+	if err != nil {
+		return nil, err
+	}
+	var rules []Rule
+	if err := json.Unmarshal(data, &rules); err != nil {
+		return nil, err
+	}
+	return rules, nil
+}
+
+// PostgresSource reads the rules admins manage through the API.
+type PostgresSource struct {
+	db *database.Queries
+}
+
+func NewPostgresSource(db *database.Queries) *PostgresSource {
+	return &PostgresSource{db: db}
+}
+
+func (s *PostgresSource) Rules(ctx context.Context) ([]Rule, error) {
+	rows, err := s.db.ListModerationRules(ctx)
+	if err != nil {
+		return nil, err
+	}
+	rules := make([]Rule, 0, len(rows))
+	for _, row := range rows {
+		rules = append(rules, Rule{Term: row.Term, Action: Action(row.Action)})
+	}
+	return rules, nil
+}
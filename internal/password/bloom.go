@@ -0,0 +1,128 @@
+package password
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"io"
+	"math"
+	"os"
+	"strings"
+)
+
+var bloomMagic = [4]byte{'C', 'H', 'B', 'F'}
+
+var ErrBloomFormat = errors.New("password: not a breached password filter")
+
+// BloomFilter is a compact, offline set of breached password SHA-1 hashes. It
+// can answer "maybe breached" for passwords that never were (at the chosen
+// false positive rate) but never misses one that was added.
+//
+// On disk it is the magic "CHBF", the number of hash functions and the number
+// of bits as big endian uint32 and uint64, then the bits as uint64 words.
+type BloomFilter struct {
+	k    uint32
+	m    uint64
+	bits []uint64
+}
+
+// NewBloomFilter sizes a filter for n entries at false positive rate p.
+func NewBloomFilter(n int, p float64) *BloomFilter {
+	if n < 1 {
+		n = 1
+	}
+	m := uint64(math.Ceil(-float64(n) * math.Log(p) / (math.Ln2 * math.Ln2)))
+	m = (m + 63) / 64 * 64
+	k := uint32(math.Round(float64(m) / float64(n) * math.Ln2))
+	if k < 1 {
+		k = 1
+	}
+	return &BloomFilter{k: k, m: m, bits: make([]uint64, m/64)}
+}
+
+// The SHA-1 sum is already uniformly distributed, so the k indexes are derived
+// from it directly by double hashing.
+func (f *BloomFilter) indexes(sum [sha1.Size]byte, fn func(uint64)) {
+	h1 := binary.BigEndian.Uint64(sum[0:8])
+	h2 := binary.BigEndian.Uint64(sum[8:16]) | 1
+	for i := uint64(0); i < uint64(f.k); i++ {
+		fn((h1 + i*h2) % f.m)
+	}
+}
+
+func (f *BloomFilter) AddSHA1(sum [sha1.Size]byte) {
+	f.indexes(sum, func(i uint64) {
+		f.bits[i/64] |= 1 << (i % 64)
+	})
+}
+
+func (f *BloomFilter) ContainsSHA1(sum [sha1.Size]byte) bool {
+	found := true
+	f.indexes(sum, func(i uint64) {
+		if f.bits[i/64]&(1<<(i%64)) == 0 {
+			found = false
+		}
+	})
+	return found
+}
+
+func (f *BloomFilter) WriteTo(w io.Writer) (int64, error) {
+	bw := bufio.NewWriter(w)
+	buf := append([]byte{}, bloomMagic[:]...)
+	buf = binary.BigEndian.AppendUint32(buf, f.k)
+	buf = binary.BigEndian.AppendUint64(buf, f.m)
+	if _, err := bw.Write(buf); err != nil {
+		return 0, err
+	}
+	if err := binary.Write(bw, binary.BigEndian, f.bits); err != nil {
+		return 0, err
+	}
+	return int64(len(buf) + 8*len(f.bits)), bw.Flush()
+}
+
+func ReadBloomFilter(r io.Reader) (*BloomFilter, error) {
+	var header [16]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return nil, ErrBloomFormat
+	}
+	if [4]byte(header[:4]) != bloomMagic {
+		return nil, ErrBloomFormat
+	}
+	f := &BloomFilter{
+		k: binary.BigEndian.Uint32(header[4:8]),
+		m: binary.BigEndian.Uint64(header[8:16]),
+	}
+	if f.k == 0 || f.m == 0 || f.m%64 != 0 {
+		return nil, ErrBloomFormat
+	}
+	f.bits = make([]uint64, f.m/64)
+	if err := binary.Read(bufio.NewReader(r), binary.BigEndian, f.bits); err != nil {
+		return nil, ErrBloomFormat
+	}
+	return f, nil
+}
+
+func LoadBloomFilter(path string) (*BloomFilter, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return ReadBloomFilter(file)
+}
+
+// ParseSHA1Line reads one line of a Have I Been Pwned style dump,
+// "HEXSHA1:count", where the count is optional.
+func ParseSHA1Line(line string) ([sha1.Size]byte, bool) {
+	var sum [sha1.Size]byte
+	hash, _, _ := strings.Cut(strings.TrimSpace(line), ":")
+	if len(hash) != 2*sha1.Size {
+		return sum, false
+	}
+	if _, err := hex.Decode(sum[:], []byte(hash)); err != nil {
+		return sum, false
+	}
+	return sum, true
+}
@@ -0,0 +1,148 @@
+// Package password decides whether a new password is acceptable.
+package password
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// BreachChecker reports whether a password shows up in a breach corpus. It is
+// handed the SHA-1 of the password, which is how those corpora are published.
+type BreachChecker interface {
+	ContainsSHA1(sum [sha1.Size]byte) bool
+}
+
+type Policy struct {
+	MinLength      int
+	MinEntropyBits float64
+	Banned         map[string]struct{}
+	Breached       BreachChecker
+}
+
+// Violation is returned by Check and lists every rule the password broke.
+type Violation struct {
+	Reasons []string
+}
+
+func (v *Violation) Error() string {
+	return "password rejected: " + strings.Join(v.Reasons, "; ")
+}
+
+// DefaultBanned is used when no banned list is configured.
+var DefaultBanned = []string{
+	"password", "password1", "passw0rd", "123456", "12345678", "123456789",
+	"qwerty", "qwertyuiop", "letmein", "welcome", "iloveyou", "admin",
+	"chirpy", "chirpy123", "changeme", "monkey", "dragon", "football",
+}
+
+func ReadBanned(r io.Reader) (map[string]struct{}, error) {
+	banned := map[string]struct{}{}
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		word := strings.ToLower(strings.TrimSpace(scanner.Text()))
+		if word != "" && !strings.HasPrefix(word, "#") {
+			banned[word] = struct{}{}
+		}
+	}
+	return banned, scanner.Err()
+}
+
+func BannedSet(words []string) map[string]struct{} {
+	banned := make(map[string]struct{}, len(words))
+	for _, w := range words {
+		banned[strings.ToLower(w)] = struct{}{}
+	}
+	return banned
+}
+
+// Check returns nil if password satisfies the policy, or a *Violation. The
+// user's own identifiers (such as their email) may be passed so the password
+// can't just repeat them.
+func (p *Policy) Check(password string, userInputs ...string) error {
+	var reasons []string
+	lower := strings.ToLower(password)
+
+	if utf8.RuneCountInString(password) < p.MinLength {
+		reasons = append(reasons, "must be at least "+strconv.Itoa(p.MinLength)+" characters")
+	}
+	if _, ok := p.Banned[lower]; ok {
+		reasons = append(reasons, "is too common")
+	}
+	for _, in := range userInputs {
+		in = strings.ToLower(in)
+		if at := strings.IndexByte(in, '@'); at > 0 {
+			in = in[:at]
+		}
+		if len(in) >= 4 && strings.Contains(lower, in) {
+			reasons = append(reasons, "must not contain your email address")
+			break
+		}
+	}
+	if Entropy(password) < p.MinEntropyBits {
+		reasons = append(reasons, "is too easy to guess, use a longer mix of words or characters")
+	}
+	if p.Breached != nil && p.Breached.ContainsSHA1(sha1.Sum([]byte(password))) {
+		reasons = append(reasons, "has appeared in a data breach")
+	}
+
+	if len(reasons) > 0 {
+		return &Violation{Reasons: reasons}
+	}
+	return nil
+}
+
+// Entropy is a rough estimate of the password's strength in bits: the size of
+// the character pool it draws from, raised to its length, where characters
+// that repeat or continue a run (aaa, abc, 123) only count half.
+func Entropy(password string) float64 {
+	var lower, upper, digit, symbol, other bool
+	effective := 0.0
+	var prev rune = -1
+	for _, r := range password {
+		switch {
+		case r >= 'a' && r <= 'z':
+			lower = true
+		case r >= 'A' && r <= 'Z':
+			upper = true
+		case r >= '0' && r <= '9':
+			digit = true
+		case r < utf8.RuneSelf && unicode.IsPrint(r):
+			symbol = true
+		default:
+			other = true
+		}
+		if r == prev || r == prev+1 || r == prev-1 {
+			effective += 0.5
+		} else {
+			effective++
+		}
+		prev = r
+	}
+
+	pool := 0
+	if lower {
+		pool += 26
+	}
+	if upper {
+		pool += 26
+	}
+	if digit {
+		pool += 10
+	}
+	if symbol {
+		pool += 33
+	}
+	if other {
+		pool += 100
+	}
+	if pool == 0 {
+		return 0
+	}
+	return effective * math.Log2(float64(pool))
+}
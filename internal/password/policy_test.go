@@ -0,0 +1,97 @@
+package password
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestPolicyCheck(t *testing.T) {
+	breached := NewBloomFilter(100, 0.001)
+	breached.AddSHA1(sha1.Sum([]byte("Tr0ub4dor&3")))
+
+	policy := Policy{
+		MinLength:      8,
+		MinEntropyBits: 40,
+		Banned:         BannedSet(DefaultBanned),
+		Breached:       breached,
+	}
+
+	tests := []struct {
+		name     string
+		password string
+		inputs   []string
+		wantErr  bool
+	}{
+		{name: "empty", password: "", wantErr: true},
+		{name: "too short", password: "x7#Lq", wantErr: true},
+		{name: "banned", password: "Password1", wantErr: true},
+		{name: "low entropy", password: "aaaaaaaaaaaa", wantErr: true},
+		{name: "sequence", password: "abcdefgh1234", wantErr: true},
+		{name: "breached", password: "Tr0ub4dor&3", wantErr: true},
+		{name: "contains email", password: "walt.whitman-Gr4ss!", inputs: []string{"walt.whitman@example.com"}, wantErr: true},
+		{name: "passphrase", password: "correct horse battery staple", wantErr: false},
+		{name: "random", password: "mQ7#vL2p!xR9", wantErr: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := policy.Check(tt.password, tt.inputs...)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Check(%q) error = %v, wantErr %v", tt.password, err, tt.wantErr)
+			}
+			var v *Violation
+			if err != nil && !errors.As(err, &v) {
+				t.Errorf("expected a *Violation, got %T", err)
+			}
+		})
+	}
+}
+
+func TestBloomFilterRoundTrip(t *testing.T) {
+	f := NewBloomFilter(1000, 0.001)
+	for i := 0; i < 1000; i++ {
+		f.AddSHA1(sha1.Sum([]byte(fmt.Sprintf("breached-%d", i))))
+	}
+
+	var buf bytes.Buffer
+	if _, err := f.WriteTo(&buf); err != nil {
+		t.Fatalf("WriteTo failed %v", err)
+	}
+	loaded, err := ReadBloomFilter(&buf)
+	if err != nil {
+		t.Fatalf("ReadBloomFilter failed %v", err)
+	}
+
+	for i := 0; i < 1000; i++ {
+		if !loaded.ContainsSHA1(sha1.Sum([]byte(fmt.Sprintf("breached-%d", i)))) {
+			t.Fatalf("expected breached-%d to be in the filter", i)
+		}
+	}
+	falsePositives := 0
+	for i := 0; i < 10000; i++ {
+		if loaded.ContainsSHA1(sha1.Sum([]byte(fmt.Sprintf("fresh-%d", i)))) {
+			falsePositives++
+		}
+	}
+	if falsePositives > 50 {
+		t.Errorf("expected about 10 false positives, got %d", falsePositives)
+	}
+
+	if _, err := ReadBloomFilter(bytes.NewReader([]byte("not a filter at all"))); !errors.Is(err, ErrBloomFormat) {
+		t.Errorf("expected ErrBloomFormat, got %v", err)
+	}
+}
+
+func TestParseSHA1Line(t *testing.T) {
+	sum := sha1.Sum([]byte("password"))
+	got, ok := ParseSHA1Line(fmt.Sprintf("%X:3861493", sum))
+	if !ok || got != sum {
+		t.Errorf("ParseSHA1Line did not read the hash back")
+	}
+	if _, ok := ParseSHA1Line("nope"); ok {
+		t.Errorf("expected a malformed line to be rejected")
+	}
+}
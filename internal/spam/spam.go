@@ -0,0 +1,159 @@
+// Package spam scores new chirps for signs of automated or abusive posting
+// and decides whether to accept, hold or reject them.
+package spam
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/anton-jj/chripy/internal/linkpreview"
+)
+
+// Signals is what is known about a chirp and its author when it is posted.
+// Only verified accounts can chirp, so verification is not a signal.
+type Signals struct {
+	Body       string
+	AccountAge time.Duration
+	// RecentPosts is how many chirps the author posted in the last hour.
+	RecentPosts int
+	// DuplicateAuthors is how many other accounts recently posted the same
+	// body.
+	DuplicateAuthors int
+}
+
+// Score is a classifier's opinion: higher is spammier, with 1 meaning "sure".
+type Score struct {
+	Value   float64
+	Reasons []string
+}
+
+// Classifier turns signals into a score. Heuristic is the built in one; a
+// trained model can be dropped in by implementing this interface.
+type Classifier interface {
+	Score(ctx context.Context, s Signals) (Score, error)
+}
+
+type Verdict string
+
+const (
+	VerdictAccept   Verdict = "accept"
+	VerdictHold     Verdict = "hold"
+	VerdictReject   Verdict = "reject"
+	VerdictThrottle Verdict = "throttle"
+)
+
+// Thresholds map a score to a verdict. Scores at or above Reject are refused,
+// at or above Hold are kept back for a moderator.
+type Thresholds struct {
+	Hold   float64
+	Reject float64
+}
+
+var DefaultThresholds = Thresholds{Hold: 0.6, Reject: 1.0}
+
+// Throttle caps how fast accounts may post, with a tighter cap while an
+// account is new.
+type Throttle struct {
+	NewAccountAge     time.Duration
+	NewAccountPerHour int
+	PerHour           int
+}
+
+var DefaultThrottle = Throttle{
+	NewAccountAge:     24 * time.Hour,
+	NewAccountPerHour: 10,
+	PerHour:           60,
+}
+
+func (t Throttle) allows(s Signals) bool {
+	limit := t.PerHour
+	if s.AccountAge < t.NewAccountAge {
+		limit = t.NewAccountPerHour
+	}
+	return limit <= 0 || s.RecentPosts < limit
+}
+
+type Decision struct {
+	Verdict Verdict
+	Score   Score
+}
+
+// Checker combines the throttle, a classifier and thresholds.
+type Checker struct {
+	Classifier Classifier
+	Thresholds Thresholds
+	Throttle   Throttle
+}
+
+// Check decides what to do with a chirp. If the classifier fails the chirp
+// is held rather than waved through.
+func (c *Checker) Check(ctx context.Context, s Signals) Decision {
+	if !c.Throttle.allows(s) {
+		return Decision{Verdict: VerdictThrottle, Score: Score{Reasons: []string{"posting too fast"}}}
+	}
+	score, err := c.Classifier.Score(ctx, s)
+	if err != nil {
+		return Decision{Verdict: VerdictHold, Score: Score{Reasons: []string{"classifier failed: " + err.Error()}}}
+	}
+	switch {
+	case score.Value >= c.Thresholds.Reject:
+		return Decision{Verdict: VerdictReject, Score: score}
+	case score.Value >= c.Thresholds.Hold:
+		return Decision{Verdict: VerdictHold, Score: score}
+	}
+	return Decision{Verdict: VerdictAccept, Score: score}
+}
+
+// Links counts the links in body the way the rest of Chirpy sees them, with
+// linkpreview.Find.
+func Links(body string) int {
+	return len(linkpreview.Find(body))
+}
+
+// Heuristic adds up fixed weights for each signal.
+type Heuristic struct{}
+
+func (Heuristic) Score(ctx context.Context, s Signals) (Score, error) {
+	var score Score
+	add := func(v float64, reason string) {
+		if v > 0 {
+			score.Value += v
+			score.Reasons = append(score.Reasons, reason)
+		}
+	}
+
+	if s.DuplicateAuthors > 0 {
+		add(0.25*math.Min(float64(s.DuplicateAuthors), 4),
+			fmt.Sprintf("same text posted by %d other accounts", s.DuplicateAuthors))
+	}
+
+	if links := Links(s.Body); links > 0 {
+		words := len(strings.Fields(s.Body))
+		density := float64(links) / float64(max(words, 1))
+		v := 0.5 * density
+		if links >= 3 {
+			v += 0.3
+		}
+		add(v, fmt.Sprintf("%d links in %d words", links, words))
+	}
+
+	newAccount := s.AccountAge < 24*time.Hour
+	if s.RecentPosts > 5 {
+		v := 0.05 * float64(s.RecentPosts-5)
+		if newAccount {
+			v *= 2
+		}
+		add(math.Min(v, 0.5), fmt.Sprintf("%d chirps in the last hour", s.RecentPosts))
+	}
+
+	switch {
+	case s.AccountAge < time.Hour:
+		add(0.3, "account is less than an hour old")
+	case newAccount:
+		add(0.15, "account is less than a day old")
+	}
+	return score, nil
+}
@@ -0,0 +1,85 @@
+package spam
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+type fixedClassifier struct {
+	value float64
+	err   error
+}
+
+func (f fixedClassifier) Score(ctx context.Context, s Signals) (Score, error) {
+	return Score{Value: f.value}, f.err
+}
+
+func TestLinks(t *testing.T) {
+	tests := []struct {
+		body string
+		want int
+	}{
+		{body: "no links here", want: 0},
+		{body: "see https://example.com/a?b=c now", want: 1},
+		{body: "www.example.org and https://cheap.xyz/offer.", want: 2},
+		{body: "bare domains like cheap.xyz are not links", want: 0},
+		{body: "version 1.2 is out", want: 0},
+	}
+	for _, tt := range tests {
+		if got := Links(tt.body); got != tt.want {
+			t.Errorf("Links(%q) = %d, want %d", tt.body, got, tt.want)
+		}
+	}
+}
+
+func TestHeuristic(t *testing.T) {
+	established := Signals{
+		Body:        "Had a lovely walk by the river today",
+		AccountAge:  90 * 24 * time.Hour,
+		RecentPosts: 1,
+	}
+	score, _ := Heuristic{}.Score(context.Background(), established)
+	if score.Value != 0 {
+		t.Errorf("expected an ordinary chirp to score 0, got %v (%v)", score.Value, score.Reasons)
+	}
+
+	flood := Signals{
+		Body:             "FREE followers https://spam.example/a https://spam.example/b cheap.xyz",
+		AccountAge:       10 * time.Minute,
+		RecentPosts:      9,
+		DuplicateAuthors: 3,
+	}
+	score, _ = Heuristic{}.Score(context.Background(), flood)
+	if score.Value < DefaultThresholds.Reject {
+		t.Errorf("expected a bot flood to reach the reject threshold, got %v (%v)", score.Value, score.Reasons)
+	}
+}
+
+func TestCheckerCheck(t *testing.T) {
+	ctx := context.Background()
+	old := Signals{AccountAge: 30 * 24 * time.Hour}
+
+	tests := []struct {
+		name       string
+		classifier Classifier
+		signals    Signals
+		want       Verdict
+	}{
+		{name: "accept", classifier: fixedClassifier{value: 0.1}, signals: old, want: VerdictAccept},
+		{name: "hold", classifier: fixedClassifier{value: 0.7}, signals: old, want: VerdictHold},
+		{name: "reject", classifier: fixedClassifier{value: 1.2}, signals: old, want: VerdictReject},
+		{name: "classifier error holds", classifier: fixedClassifier{err: errors.New("model offline")}, signals: old, want: VerdictHold},
+		{name: "new account throttled", classifier: fixedClassifier{}, signals: Signals{AccountAge: time.Hour, RecentPosts: 10}, want: VerdictThrottle},
+		{name: "old account not throttled", classifier: fixedClassifier{}, signals: Signals{AccountAge: 30 * 24 * time.Hour, RecentPosts: 10}, want: VerdictAccept},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := Checker{Classifier: tt.classifier, Thresholds: DefaultThresholds, Throttle: DefaultThrottle}
+			if got := c.Check(ctx, tt.signals).Verdict; got != tt.want {
+				t.Errorf("Check() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,9 @@
+package textlen
+
+import "github.com/rivo/uniseg"
+
+// Graphemes counts the extended grapheme clusters in s, the units a reader
+// sees as single characters, as defined by UAX #29.
+func Graphemes(s string) int {
+	return uniseg.GraphemeClusterCount(s)
+}
@@ -0,0 +1,43 @@
+// Package textlen measures chirps the way readers see them: in characters
+// rather than bytes, with links counted at a fixed weight so a long URL
+// costs no more than a short one.
+package textlen
+
+import (
+	"github.com/anton-jj/chripy/internal/linkpreview"
+	"golang.org/x/text/unicode/norm"
+)
+
+// DefaultURLWeight is what a link counts for unless a Counter says otherwise.
+const DefaultURLWeight = 23
+
+// Counter measures text. The zero value counts links at DefaultURLWeight.
+type Counter struct {
+	// URLWeight is the length every link counts for, however long it is.
+	URLWeight int
+}
+
+// Normalize returns text in Unicode normalization form C, the form chirps
+// are stored and measured in.
+func Normalize(text string) string {
+	return norm.NFC.String(text)
+}
+
+// Length returns the weighted length of text: each link, as linkpreview.Find
+// sees it, counts as URLWeight and everything else as one per grapheme
+// cluster after NFC normalization. Links over linkpreview.MaxURLLength are
+// not links, so they are measured as ordinary text.
+func (c Counter) Length(text string) int {
+	weight := c.URLWeight
+	if weight <= 0 {
+		weight = DefaultURLWeight
+	}
+	runes := []rune(Normalize(text))
+	length := 0
+	last := 0
+	for _, link := range linkpreview.Find(string(runes)) {
+		length += Graphemes(string(runes[last:link.Start])) + weight
+		last = link.End
+	}
+	return length + Graphemes(string(runes[last:]))
+}
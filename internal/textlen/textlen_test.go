@@ -0,0 +1,68 @@
+package textlen
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/anton-jj/chripy/internal/linkpreview"
+)
+
+func TestGraphemes(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want int
+	}{
+		{"ascii", "hello", 5},
+		{"empty", "", 0},
+		{"combining accent", "e\u0301te\u0301", 3},
+		{"cjk", "你好世界", 4},
+		{"hangul syllables", "한국어", 3},
+		{"hangul jamo", "한", 1},
+		{"skin tone", "👍🏽", 1},
+		{"zwj family", "👨‍👩‍👧‍👦", 1},
+		{"flags", "🇸🇪🇫🇮", 2},
+		{"odd regional indicator", "🇸🇪🇫", 2},
+		{"variation selector", "❤️", 1},
+		{"keycap", "1️⃣", 1},
+		{"tag sequence", "🏴\U000E0067\U000E0062\U000E0073\U000E0063\U000E0074\U000E007F", 1},
+		{"crlf", "a\r\nb", 3},
+		{"devanagari", "नमस्ते", 4},
+		{"prepend", "\u0600\u0661\u0662", 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Graphemes(tt.text); got != tt.want {
+				t.Errorf("Graphemes(%q) = %d, want %d", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLength(t *testing.T) {
+	var c Counter
+	tests := []struct {
+		name string
+		text string
+		want int
+	}{
+		{"plain", "hello world", 11},
+		{"decomposed is normalized", "cafe\u0301", 4},
+		{"emoji", strings.Repeat("😀", 140), 140},
+		{"link", "read https://example.com/a/very/long/path/that/goes/on?and=on now", 5 + DefaultURLWeight + 4},
+		{"www link", "www.example.org", DefaultURLWeight},
+		{"two links", "http://a.io http://b.io", 2*DefaultURLWeight + 1},
+		{"oversized link", "https://a.io/" + strings.Repeat("x", linkpreview.MaxURLLength), 13 + linkpreview.MaxURLLength},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := c.Length(tt.text); got != tt.want {
+				t.Errorf("Length(%q) = %d, want %d", tt.text, got, tt.want)
+			}
+		})
+	}
+
+	if got := (Counter{URLWeight: 5}).Length("see https://example.com"); got != 9 {
+		t.Errorf("custom weight: got %d, want 9", got)
+	}
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/anton-jj/chripy/internal/database"
+	"github.com/anton-jj/chripy/internal/linkpreview"
+	"github.com/google/uuid"
+)
+
+// chirpLink is a link entity in a chirp. Start and End count runes into the
+// body.
+type chirpLink struct {
+	URL   string `json:"url"`
+	Start int    `json:"start"`
+	End   int    `json:"end"`
+}
+
+// newLinkPreviewWorker builds the worker that fetches link previews. The
+// fetch is bounded by LINK_PREVIEW_TIMEOUT and LINK_PREVIEW_MAX_BYTES.
+func newLinkPreviewWorker(db *database.Queries) *linkpreview.Worker {
+	fetcher := linkpreview.NewHTTPFetcher(linkpreview.Options{
+		Timeout:  envDuration("LINK_PREVIEW_TIMEOUT", linkpreview.DefaultTimeout),
+		MaxBytes: int64(envInt("LINK_PREVIEW_MAX_BYTES", linkpreview.DefaultMaxBytes)),
+	})
+	worker := linkpreview.NewWorker(fetcher, linkpreview.NewPostgresStore(db))
+	worker.Interval = envDuration("LINK_PREVIEW_INTERVAL", linkpreview.DefaultInterval)
+	return worker
+}
+
+// storeChirpLinks records the links in a new chirp and queues their
+// previews. Failures are logged; the chirp is posted either way.
+func (aCfg *apiConfig) storeChirpLinks(r *http.Request, chirp database.Chirp) {
+	entities := linkpreview.Extract(chirp.Body)
+	for i, e := range entities {
+		if err := aCfg.db.EnsureLinkPreview(r.Context(), e.URL); err != nil {
+			log.Printf("failed to queue link preview for %s: %v", e.URL, err)
+			return
+		}
+		err := aCfg.db.CreateChirpLink(r.Context(), database.CreateChirpLinkParams{
+			ChirpID:    chirp.ID,
+			Position:   int32(i),
+			Url:        e.URL,
+			StartIndex: int32(e.Start),
+			EndIndex:   int32(e.End),
+		})
+		if err != nil {
+			log.Printf("failed to store links for chirp %s: %v", chirp.ID, err)
+			return
+		}
+	}
+	if len(entities) > 0 {
+		aCfg.linkPreviews.Notify()
+	}
+}
+
+// attachLinks fills in the link entities of chirps, and the preview card of
+// the first link whose preview is ready.
+func (aCfg *apiConfig) attachLinks(r *http.Request, chirps []Chirp) {
+	if len(chirps) == 0 {
+		return
+	}
+	ids := make([]uuid.UUID, len(chirps))
+	index := make(map[uuid.UUID]int, len(chirps))
+	for i, c := range chirps {
+		ids[i] = c.Id
+		index[c.Id] = i
+	}
+	rows, err := aCfg.db.GetChirpLinks(r.Context(), ids)
+	if err != nil {
+		log.Printf("failed to get chirp links: %v", err)
+		return
+	}
+	for _, row := range rows {
+		c := &chirps[index[row.ChirpID]]
+		c.Links = append(c.Links, chirpLink{
+			URL:   row.Url,
+			Start: int(row.StartIndex),
+			End:   int(row.EndIndex),
+		})
+		if c.Preview == nil && row.Status == "ready" {
+			c.Preview = &linkpreview.Preview{
+				URL:         row.Url,
+				Title:       row.Title,
+				Description: row.Description,
+				ImageURL:    row.ImageUrl,
+				SiteName:    row.SiteName,
+			}
+		}
+	}
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/anton-jj/chripy/internal/audit"
+	"github.com/anton-jj/chripy/internal/auth"
+	"github.com/anton-jj/chripy/internal/lockout"
+	"github.com/anton-jj/chripy/internal/mailer"
+	"github.com/google/uuid"
+)
+
+var (
+	accountLockoutPolicy = lockout.Policy{
+		FreeAttempts:     5,
+		BaseDelay:        time.Second,
+		MaxDelay:         time.Minute,
+		LockoutThreshold: 10,
+		LockoutDuration:  15 * time.Minute,
+		Window:           time.Hour,
+	}
+	// One address may legitimately front many users (NAT, offices), so it gets
+	// more room before backing off, and no hard lockout.
+	ipLockoutPolicy = lockout.Policy{
+		FreeAttempts: 20,
+		BaseDelay:    time.Second,
+		MaxDelay:     5 * time.Minute,
+		Window:       time.Hour,
+	}
+)
+
+// dummyHash is compared against when the email is unknown, so a failed login
+// takes as long whether or not the account exists.
+var dummyHash = sync.OnceValue(func() string {
+	hash, err := auth.HashPassword("chirpy-dummy-password")
+	if err != nil {
+		log.Printf("failed to create dummy hash: %v", err)
+	}
+	return hash
+})
+
+func newLockoutStore(kind string, aCfg *apiConfig) lockout.Store {
+	if kind == "postgres" {
+		return lockout.NewPostgresStore(aCfg.db)
+	}
+	return lockout.NewMemoryStore()
+}
+
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+func accountKey(email string) string {
+	return "account:" + strings.ToLower(strings.TrimSpace(email))
+}
+
+// loginRetryAfter reports how long a login for email from this client has to
+// wait. Storage errors fail open so an outage does not lock everybody out.
+func (aCfg *apiConfig) loginRetryAfter(r *http.Request, email string) time.Duration {
+	now := time.Now()
+	accountWait, err := aCfg.accountLimiter.Check(r.Context(), accountKey(email), now)
+	if err != nil {
+		log.Printf("login limiter: %v", err)
+	}
+	ipWait, err := aCfg.ipLimiter.Check(r.Context(), "ip:"+clientIP(r), now)
+	if err != nil {
+		log.Printf("login limiter: %v", err)
+	}
+	return max(accountWait, ipWait)
+}
+
+func respondTooManyAttempts(w http.ResponseWriter, wait time.Duration) {
+	w.Header().Set("Retry-After", fmt.Sprint(int(math.Ceil(wait.Seconds()))))
+	respondWithError(w, 429, "Too many login attempts, try again later")
+}
+
+// loginFailed records a failed attempt. When it locks the account, the owner
+// (if the email belongs to one) is told about it.
+func (aCfg *apiConfig) loginFailed(r *http.Request, email string) {
+	aCfg.recordAudit(r, audit.ActionLoginFailed, uuid.Nil, uuid.Nil, map[string]interface{}{
+		"email": email,
+		"path":  r.URL.Path,
+	})
+	now := time.Now()
+	if _, err := aCfg.ipLimiter.Fail(r.Context(), "ip:"+clientIP(r), now); err != nil {
+		log.Printf("login limiter: %v", err)
+	}
+	locked, err := aCfg.accountLimiter.Fail(r.Context(), accountKey(email), now)
+	if err != nil {
+		log.Printf("login limiter: %v", err)
+		return
+	}
+	if locked {
+		go aCfg.sendLockoutNotice(email, clientIP(r))
+	}
+}
+
+func (aCfg *apiConfig) loginSucceeded(r *http.Request, email string) {
+	if err := aCfg.accountLimiter.Succeed(r.Context(), accountKey(email)); err != nil {
+		log.Printf("login limiter: %v", err)
+	}
+}
+
+func (aCfg *apiConfig) sendLockoutNotice(email, ip string) {
+	ctx := context.Background()
+	user, err := aCfg.db.GetUserByEmail(ctx, email)
+	if err != nil {
+		return
+	}
+	err = aCfg.mailer.Send(ctx, mailer.Message{
+		To:      user.Email,
+		Subject: "Your Chirpy account was temporarily locked",
+		Body: fmt.Sprintf("We saw too many failed sign-in attempts on your Chirpy account (the last one from %s), so we locked it for %d minutes.\n\nIf this wasn't you, consider resetting your password and enabling two-factor authentication.\n",
+			ip, int(accountLockoutPolicy.LockoutDuration.Minutes())),
+	})
+	if err != nil {
+		log.Printf("failed to send lockout notice to %s: %v", user.ID, err)
+	}
+}
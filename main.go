@@ -1,17 +1,30 @@
 package main
 
 import (
+	"context"
 	"database/sql"
 	"encoding/json"
+	"fmt"
 	"log"
+	"math"
 	"net/http"
 	"os"
-	"strings"
+	"runtime"
+	"strconv"
 	"sync/atomic"
 	"time"
 
+	"github.com/alexedwards/argon2id"
+	"github.com/anton-jj/chripy/internal/audit"
 	"github.com/anton-jj/chripy/internal/auth"
 	"github.com/anton-jj/chripy/internal/database"
+	"github.com/anton-jj/chripy/internal/linkpreview"
+	"github.com/anton-jj/chripy/internal/lockout"
+	"github.com/anton-jj/chripy/internal/mailer"
+	"github.com/anton-jj/chripy/internal/moderation"
+	"github.com/anton-jj/chripy/internal/password"
+	"github.com/anton-jj/chripy/internal/spam"
+	"github.com/anton-jj/chripy/internal/textlen"
 	"github.com/joho/godotenv"
 	_ "github.com/lib/pq"
 )
@@ -24,10 +37,25 @@ type apiConfig struct {
 	fileServerHits atomic.Int32
 	db             *database.Queries
 	secret         string
-}
-
-type cleanedData struct {
-	Cleaned_body string `json:"cleaned_body"`
+	webAuthn       auth.WebAuthn
+	mailer         mailer.Mailer
+	baseURL        string
+	accountLimiter *lockout.Limiter
+	ipLimiter      *lockout.Limiter
+	passwordPolicy *password.Policy
+	auditLog       *audit.Logger
+	moderation     *moderation.Engine
+	spamChecker    *spam.Checker
+	chirpLength    textlen.Counter
+	// chirpMaxLength is the longest a chirp may be, as measured by chirpLength.
+	chirpMaxLength int
+	linkPreviews   *linkpreview.Worker
+	reactions      reactionSet
+
+	deletionGracePeriod time.Duration
+	// devMode enables destructive helpers such as POST /admin/reset. It is only
+	// on when PLATFORM=dev.
+	devMode bool
 }
 
 func main() {
@@ -37,11 +65,32 @@ func main() {
 	}
 	dbURL := os.Getenv("DB_URL")
 	secret := os.Getenv("SECRET")
+	baseURL := os.Getenv("BASE_URL")
+	if baseURL == "" {
+		baseURL = "http://localhost:8080"
+	}
+	rpID := os.Getenv("WEBAUTHN_RP_ID")
+	if rpID == "" {
+		rpID = "localhost"
+	}
+	rpOrigin := os.Getenv("WEBAUTHN_ORIGIN")
+	if rpOrigin == "" {
+		rpOrigin = baseURL
+	}
 	db, err := sql.Open("postgres", dbURL)
 	if err != nil {
 		os.Exit(1)
 	}
 	dbQueries := database.New(db)
+	hashConfig, err := hashConfigFromEnv()
+	if err != nil {
+		log.Fatal(err)
+	}
+	auth.ConfigureHashing(hashConfig)
+	passwordPolicy, err := passwordPolicyFromEnv()
+	if err != nil {
+		log.Fatal(err)
+	}
 	const filePathRoot = "."
 	const port = ":8080"
 
@@ -49,24 +98,109 @@ func main() {
 		fileServerHits: atomic.Int32{},
 		db:             dbQueries,
 		secret:         secret,
+		webAuthn: auth.WebAuthn{
+			RPID:   rpID,
+			RPName: "Chirpy",
+			Origin: rpOrigin,
+		},
+		mailer:         newMailer(),
+		baseURL:        baseURL,
+		passwordPolicy: passwordPolicy,
+		auditLog:       audit.New(audit.NewPostgresStore(dbQueries)),
+		spamChecker:    newSpamChecker(),
+		chirpLength:    textlen.Counter{URLWeight: envInt("CHIRP_URL_WEIGHT", textlen.DefaultURLWeight)},
+		chirpMaxLength: envInt("CHIRP_MAX_LENGTH", 140),
+		linkPreviews:   newLinkPreviewWorker(dbQueries),
+		reactions:      reactionsFromEnv(),
+
+		deletionGracePeriod: envDuration("ACCOUNT_DELETION_GRACE", 30*24*time.Hour),
+		devMode:             os.Getenv("PLATFORM") == "dev",
 	}
+	lockoutStore := newLockoutStore(os.Getenv("LOGIN_LIMITER_STORE"), &apiConfig)
+	apiConfig.accountLimiter = lockout.New(lockoutStore, accountLockoutPolicy)
+	apiConfig.ipLimiter = lockout.New(lockoutStore, ipLockoutPolicy)
+
+	apiConfig.moderation = newModerationEngine(dbQueries)
+	go apiConfig.moderation.Watch(context.Background(), envDuration("MODERATION_RELOAD_INTERVAL", moderationReloadInterval))
+	go apiConfig.purgeDeletedAccounts(context.Background(), accountPurgeInterval)
+	go apiConfig.linkPreviews.Run(context.Background())
 
 	mux := http.NewServeMux()
 	fsHandler := apiConfig.middlewareMetricsInc(http.StripPrefix("/app", http.FileServer(http.Dir(filePathRoot))))
 	mux.Handle("/app/", fsHandler)
 
 	mux.HandleFunc("GET /api/healthz", handleHealtz)
-	mux.HandleFunc("GET /admin/metrics", apiConfig.handleMetrics)
-	mux.HandleFunc("POST /admin/reset", apiConfig.handleReset)
+	mux.HandleFunc("GET /admin/metrics", apiConfig.middlewareRequirePermission(permViewMetrics, apiConfig.handleMetrics))
+	mux.HandleFunc("POST /admin/reset", apiConfig.middlewareRequirePermission(permResetDatabase, apiConfig.handleReset))
+	mux.HandleFunc("GET /admin/audit", apiConfig.middlewareRequirePermission(permViewAudit, apiConfig.handleAdminAuditEvents))
+	mux.HandleFunc("GET /admin/moderation/rules", apiConfig.middlewareRequirePermission(permModerate, apiConfig.handleListModerationRules))
+	mux.HandleFunc("POST /admin/moderation/rules", apiConfig.middlewareRequirePermission(permModerate, apiConfig.handleCreateModerationRule))
+	mux.HandleFunc("PATCH /admin/moderation/rules/{ruleID}", apiConfig.middlewareRequirePermission(permModerate, apiConfig.handleUpdateModerationRule))
+	mux.HandleFunc("DELETE /admin/moderation/rules/{ruleID}", apiConfig.middlewareRequirePermission(permModerate, apiConfig.handleDeleteModerationRule))
+	mux.HandleFunc("POST /admin/moderation/reload", apiConfig.middlewareRequirePermission(permModerate, apiConfig.handleReloadModeration))
+	mux.HandleFunc("PATCH /admin/chirps/{chirpID}", apiConfig.middlewareRequirePermission(permModerate, apiConfig.handleAdminSetChirpSensitivity))
+	mux.HandleFunc("GET /admin/reports", apiConfig.middlewareRequirePermission(permModerate, apiConfig.handleAdminListReports))
+	mux.HandleFunc("GET /admin/reports/{reportID}", apiConfig.middlewareRequirePermission(permModerate, apiConfig.handleAdminGetReport))
+	mux.HandleFunc("POST /admin/reports/{reportID}/resolve", apiConfig.middlewareRequirePermission(permModerate, apiConfig.handleAdminResolveReport))
+	mux.HandleFunc("GET /admin/users", apiConfig.middlewareRequirePermission(permManageUsers, apiConfig.handleAdminListUsers))
+	mux.HandleFunc("GET /admin/users/{userID}", apiConfig.middlewareRequirePermission(permManageUsers, apiConfig.handleAdminGetUser))
+	mux.HandleFunc("GET /admin/users/{userID}/sessions", apiConfig.middlewareRequirePermission(permManageUsers, apiConfig.handleAdminUserSessions))
+	mux.HandleFunc("GET /admin/users/{userID}/chirps", apiConfig.middlewareRequirePermission(permManageUsers, apiConfig.handleAdminUserChirps))
+	mux.HandleFunc("POST /admin/users/{userID}/suspend", apiConfig.middlewareRequirePermission(permManageUsers, apiConfig.handleAdminSuspendUser))
+	mux.HandleFunc("POST /admin/users/{userID}/unsuspend", apiConfig.middlewareRequirePermission(permManageUsers, apiConfig.handleAdminUnsuspendUser))
+	mux.HandleFunc("POST /admin/users/{userID}/limit", apiConfig.middlewareRequirePermission(permModerate, apiConfig.handleAdminLimitUser))
+	mux.HandleFunc("POST /admin/users/{userID}/unlimit", apiConfig.middlewareRequirePermission(permModerate, apiConfig.handleAdminUnlimitUser))
+	mux.HandleFunc("POST /admin/users/{userID}/password-reset", apiConfig.middlewareRequirePermission(permManageUsers, apiConfig.handleAdminForcePasswordReset))
+	mux.HandleFunc("POST /admin/users/{userID}/revoke-tokens", apiConfig.middlewareRequirePermission(permManageUsers, apiConfig.handleAdminRevokeTokens))
 	mux.HandleFunc("POST /api/users", apiConfig.handleUsers)
+	mux.HandleFunc("POST /api/users/verify", apiConfig.handleVerifyEmail)
+	mux.HandleFunc("POST /api/users/verify/resend", apiConfig.middlewareRequireAuth("", apiConfig.handleResendVerification))
+	mux.HandleFunc("POST /api/password/forgot", apiConfig.handleForgotPassword)
+	mux.HandleFunc("POST /api/password/reset", apiConfig.handleResetPassword)
 	mux.HandleFunc("POST /api/login", apiConfig.handleLogin)
-	mux.HandleFunc("POST /api/chirps", apiConfig.handleChirpCreate)
-	mux.HandleFunc("GET /api/chirps", apiConfig.handleChirpsGetAll)
+	mux.HandleFunc("POST /api/login/2fa", apiConfig.handleLoginTOTP)
+	mux.HandleFunc("POST /api/login/passkey/begin", apiConfig.handlePasskeyLoginBegin)
+	mux.HandleFunc("POST /api/login/passkey/finish", apiConfig.handlePasskeyLoginFinish)
+	mux.HandleFunc("POST /api/users/passkeys/register/begin", apiConfig.middlewareRequireAuth("", apiConfig.handlePasskeyRegisterBegin))
+	mux.HandleFunc("POST /api/users/passkeys/register/finish", apiConfig.middlewareRequireAuth("", apiConfig.handlePasskeyRegisterFinish))
+	mux.HandleFunc("GET /api/users/passkeys", apiConfig.middlewareRequireAuth("", apiConfig.handleListPasskeys))
+	mux.HandleFunc("DELETE /api/users/passkeys/{passkeyID}", apiConfig.middlewareRequireAuth("", apiConfig.handleDeletePasskey))
+	mux.HandleFunc("POST /api/users/2fa/setup", apiConfig.middlewareRequireAuth("", apiConfig.handleTOTPSetup))
+	mux.HandleFunc("POST /api/users/2fa/confirm", apiConfig.middlewareRequireAuth("", apiConfig.handleTOTPConfirm))
+	mux.HandleFunc("POST /api/users/2fa/disable", apiConfig.middlewareRequireAuth("", apiConfig.handleTOTPDisable))
+	mux.HandleFunc("POST /api/chirps", apiConfig.middlewareRequireAuth(auth.ScopeChirpsWrite, apiConfig.handleChirpCreate))
+	mux.HandleFunc("GET /api/chirps", apiConfig.middlewareOptionalAuth(auth.ScopeChirpsRead, apiConfig.handleChirpsGetAll))
 	mux.HandleFunc("POST /api/refresh", apiConfig.handleRefresh)
 	mux.HandleFunc("POST /api/revoke", apiConfig.handleRevoke)
-	mux.HandleFunc("PUT /api/users", apiConfig.handleUpdateUser)
-	mux.HandleFunc("GET /api/chirps/{chirpID}", apiConfig.handleGetChirp)
-	mux.HandleFunc("DELETE /api/chirps/{chirpID}", apiConfig.handleDeleteChirp)
+	mux.HandleFunc("PATCH /api/users/me", apiConfig.middlewareRequireAuth(auth.ScopeProfileWrite, apiConfig.handleUpdateUser))
+	mux.HandleFunc("DELETE /api/users/me", apiConfig.middlewareRequireAuth("", apiConfig.handleDeleteUser))
+	mux.HandleFunc("POST /api/users/me/restore", apiConfig.middlewareRequireAuth("", apiConfig.handleRestoreUser))
+	mux.HandleFunc("GET /api/users/me/export", apiConfig.middlewareRequireAuth("", apiConfig.handleExportUser))
+	mux.HandleFunc("GET /api/chirps/{chirpID}", apiConfig.middlewareOptionalAuth(auth.ScopeChirpsRead, apiConfig.handleGetChirp))
+	mux.HandleFunc("DELETE /api/chirps/{chirpID}", apiConfig.middlewareRequireAuth(auth.ScopeChirpsWrite, apiConfig.handleDeleteChirp))
+	mux.HandleFunc("PUT /api/chirps/{chirpID}/reactions/{emoji}", apiConfig.middlewareRequireAuth(auth.ScopeChirpsWrite, apiConfig.handleAddReaction))
+	mux.HandleFunc("DELETE /api/chirps/{chirpID}/reactions/{emoji}", apiConfig.middlewareRequireAuth(auth.ScopeChirpsWrite, apiConfig.handleRemoveReaction))
+	mux.HandleFunc("POST /api/chirps/{chirpID}/report", apiConfig.middlewareRequireAuth("", apiConfig.handleReportChirp))
+	mux.HandleFunc("POST /api/users/{userID}/report", apiConfig.middlewareRequireAuth("", apiConfig.handleReportUser))
+	mux.HandleFunc("GET /api/blocks", apiConfig.middlewareRequireAuth("", apiConfig.handleListBlocks))
+	mux.HandleFunc("PUT /api/blocks/{userID}", apiConfig.middlewareRequireAuth("", apiConfig.handleBlockUser))
+	mux.HandleFunc("DELETE /api/blocks/{userID}", apiConfig.middlewareRequireAuth("", apiConfig.handleUnblockUser))
+	mux.HandleFunc("GET /api/filters", apiConfig.middlewareRequireAuth("", apiConfig.handleListKeywordFilters))
+	mux.HandleFunc("POST /api/filters", apiConfig.middlewareRequireAuth("", apiConfig.handleCreateKeywordFilter))
+	mux.HandleFunc("PUT /api/filters/{filterID}", apiConfig.middlewareRequireAuth("", apiConfig.handleReplaceKeywordFilter))
+	mux.HandleFunc("DELETE /api/filters/{filterID}", apiConfig.middlewareRequireAuth("", apiConfig.handleDeleteKeywordFilter))
+	mux.HandleFunc("GET /api/mutes", apiConfig.middlewareRequireAuth("", apiConfig.handleListMutes))
+	mux.HandleFunc("PUT /api/mutes/{userID}", apiConfig.middlewareRequireAuth("", apiConfig.handleMuteUser))
+	mux.HandleFunc("DELETE /api/mutes/{userID}", apiConfig.middlewareRequireAuth("", apiConfig.handleUnmuteUser))
+	mux.HandleFunc("POST /api/keys", apiConfig.middlewareRequireAuth("", apiConfig.handleCreateAPIKey))
+	mux.HandleFunc("GET /api/keys", apiConfig.middlewareRequireAuth("", apiConfig.handleListAPIKeys))
+	mux.HandleFunc("DELETE /api/keys/{keyID}", apiConfig.middlewareRequireAuth("", apiConfig.handleDeleteAPIKey))
+	mux.HandleFunc("POST /api/oauth/clients", apiConfig.middlewareRequireAuth("", apiConfig.handleCreateOAuthClient))
+	mux.HandleFunc("GET /oauth/authorize", apiConfig.handleOAuthAuthorize)
+	mux.HandleFunc("POST /oauth/authorize", apiConfig.handleOAuthConsent)
+	mux.HandleFunc("POST /oauth/token", apiConfig.handleOAuthToken)
+	mux.HandleFunc("POST /oauth/introspect", apiConfig.handleOAuthIntrospect)
+	mux.HandleFunc("POST /oauth/revoke", apiConfig.handleOAuthRevoke)
 
 	server := &http.Server{
 		Addr:    port,
@@ -74,7 +208,141 @@ func main() {
 	}
 
 	log.Printf("Serving files from %s to port: %s", filePathRoot, server.Addr)
-	server.ListenAndServe().Error()
+	log.Fatal(server.ListenAndServe())
+}
+
+// newModerationEngine loads the rules admins manage in the database, plus the
+// rules in MODERATION_RULES_FILE when it is set. A failed first load is
+// logged and leaves moderation empty until the next reload succeeds.
+func newModerationEngine(db *database.Queries) *moderation.Engine {
+	sources := []moderation.Source{moderation.NewPostgresSource(db)}
+	if path := os.Getenv("MODERATION_RULES_FILE"); path != "" {
+		sources = append(sources, moderation.FileSource{Path: path})
+	}
+	engine := moderation.New(sources...)
+	if err := engine.Reload(context.Background()); err != nil {
+		log.Printf("moderation: failed to load rules: %v", err)
+	}
+	return engine
+}
+
+// passwordPolicyFromEnv builds the policy for new passwords. PASSWORD_MIN_LENGTH
+// and PASSWORD_MIN_ENTROPY tune the strength requirements, PASSWORD_BANNED_FILE
+// replaces the built in list of common passwords and BREACHED_PASSWORDS_FILE
+// points at a filter built with cmd/breachfilter.
+func passwordPolicyFromEnv() (*password.Policy, error) {
+	policy := &password.Policy{
+		MinLength:      envInt("PASSWORD_MIN_LENGTH", 10),
+		MinEntropyBits: float64(envInt("PASSWORD_MIN_ENTROPY", 40)),
+		Banned:         password.BannedSet(password.DefaultBanned),
+	}
+	if path := os.Getenv("PASSWORD_BANNED_FILE"); path != "" {
+		file, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		defer file.Close()
+		policy.Banned, err = password.ReadBanned(file)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if path := os.Getenv("BREACHED_PASSWORDS_FILE"); path != "" {
+		filter, err := password.LoadBloomFilter(path)
+		if err != nil {
+			return nil, err
+		}
+		policy.Breached = filter
+	}
+	return policy, nil
+}
+
+// hashConfigFromEnv reads the argon2id cost from ARGON2_MEMORY_KIB,
+// ARGON2_ITERATIONS and ARGON2_PARALLELISM, and the number of hashes allowed
+// to run at once from HASH_CONCURRENCY. Unset values keep the defaults; a cost
+// that is set but out of range is an error rather than a silent fallback.
+func hashConfigFromEnv() (auth.HashConfig, error) {
+	params := *argon2id.DefaultParams
+	memory, err := envIntRange("ARGON2_MEMORY_KIB", int(params.Memory), 1, math.MaxUint32)
+	if err != nil {
+		return auth.HashConfig{}, err
+	}
+	iterations, err := envIntRange("ARGON2_ITERATIONS", int(params.Iterations), 1, math.MaxUint32)
+	if err != nil {
+		return auth.HashConfig{}, err
+	}
+	parallelism, err := envIntRange("ARGON2_PARALLELISM", int(params.Parallelism), 1, math.MaxUint8)
+	if err != nil {
+		return auth.HashConfig{}, err
+	}
+	params.Memory = uint32(memory)
+	params.Iterations = uint32(iterations)
+	params.Parallelism = uint8(parallelism)
+	return auth.HashConfig{
+		Params:        &params,
+		MaxConcurrent: envInt("HASH_CONCURRENCY", runtime.NumCPU()),
+	}, nil
+}
+
+// envIntRange reads name as an integer between lo and hi inclusive, or
+// returns fallback when it is unset.
+func envIntRange(name string, fallback, lo, hi int) (int, error) {
+	v := os.Getenv(name)
+	if v == "" {
+		return fallback, nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n < lo || n > hi {
+		return 0, fmt.Errorf("%s=%q must be a whole number from %d to %d", name, v, lo, hi)
+	}
+	return n, nil
+}
+
+func envInt(name string, fallback int) int {
+	v := os.Getenv(name)
+	if v == "" {
+		return fallback
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n <= 0 {
+		log.Printf("ignoring invalid %s=%q", name, v)
+		return fallback
+	}
+	return n
+}
+
+// envDuration reads a Go duration such as "720h". Zero is allowed so features
+// with a delay can be switched to immediate.
+func envDuration(name string, fallback time.Duration) time.Duration {
+	v := os.Getenv(name)
+	if v == "" {
+		return fallback
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil || d < 0 {
+		log.Printf("ignoring invalid %s=%q", name, v)
+		return fallback
+	}
+	return d
+}
+
+// newMailer picks the mail transport from MAILER: "smtp" for production, or
+// "file" (the default) which drops messages into MAIL_DIR for local dev.
+func newMailer() mailer.Mailer {
+	from := os.Getenv("MAIL_FROM")
+	if from == "" {
+		from = "Chirpy <no-reply@chirpy.local>"
+	}
+	switch os.Getenv("MAILER") {
+	case "smtp":
+		return mailer.NewSMTPMailer(os.Getenv("SMTP_ADDR"), from, os.Getenv("SMTP_USER"), os.Getenv("SMTP_PASSWORD"))
+	default:
+		dir := os.Getenv("MAIL_DIR")
+		if dir == "" {
+			dir = "mail"
+		}
+		return &mailer.FileMailer{Dir: dir, From: from}
+	}
 }
 
 func handleHealtz(w http.ResponseWriter, r *http.Request) {
@@ -108,6 +376,7 @@ func (aCfg *apiConfig) handleRevoke(w http.ResponseWriter, r *http.Request) {
 		respondWithError(w, 401, "Failed to update token in database")
 		return
 	}
+	aCfg.recordAudit(r, audit.ActionTokenRevoked, token.UserID, token.UserID, nil)
 
 	respondWithJson(w, 204, nil)
 
@@ -142,37 +411,23 @@ func (aCfg *apiConfig) handleRefresh(w http.ResponseWriter, r *http.Request) {
 		return
 	
 	}
+	if user.SuspendedAt.Valid {
+		respondWithError(w, 403, "account suspended")
+		return
+	}
 
 	newJWT, err := auth.MakeJWT(user.ID, aCfg.secret, time.Hour)
 	if err != nil {
 		respondWithError(w, 500, "failed to create new jwt")
 		return
 	}
+	aCfg.recordAudit(r, audit.ActionTokenRefreshed, user.ID, user.ID, nil)
 
 
 	respondWithJson(w, 200, struct{ Token string `json:"token"`} {Token: newJWT})
 
 }
 
-func validateBody(data string) string {
-	badWords := map[string]int{
-		"kerfuffle": 0,
-		"sharbert":  0,
-		"fornax":    0,
-	}
-	var cleanedData []string
-	for _, s := range strings.Split(data, " ") {
-		if _, ok := badWords[strings.ToLower(s)]; ok {
-			cleanedData = append(cleanedData, "****")
-		} else {
-
-			cleanedData = append(cleanedData, s)
-		}
-
-	}
-	return strings.Join(cleanedData, " ")
-}
-
 func respondWithError(w http.ResponseWriter, code int, message string) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(code)
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/anton-jj/chripy/internal/audit"
+	"github.com/anton-jj/chripy/internal/auth"
+	"github.com/anton-jj/chripy/internal/database"
+	"github.com/anton-jj/chripy/internal/lockout"
+	"github.com/google/uuid"
+	_ "github.com/lib/pq"
+)
+
+// testAPIConfig connects to TEST_DATABASE_URL, a database with every
+// migration in sql/schema applied. Tests that need it are skipped when it
+// isn't set.
+func testAPIConfig(t *testing.T) *apiConfig {
+	t.Helper()
+	url := os.Getenv("TEST_DATABASE_URL")
+	if url == "" {
+		t.Skip("TEST_DATABASE_URL is not set")
+	}
+	db, err := sql.Open("postgres", url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return &apiConfig{
+		db:       database.New(db),
+		secret:   "test-secret",
+		auditLog: audit.New(audit.NewMemoryStore()),
+
+		accountLimiter: lockout.New(lockout.NewMemoryStore(), accountLockoutPolicy),
+		ipLimiter:      lockout.New(lockout.NewMemoryStore(), ipLockoutPolicy),
+	}
+}
+
+func createTestUser(t *testing.T, aCfg *apiConfig, role string) database.User {
+	t.Helper()
+	ctx := context.Background()
+	user, err := aCfg.db.CreateUser(ctx, database.CreateUserParams{
+		Email:          uuid.NewString() + "@example.com",
+		HashedPassword: "unused",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { aCfg.db.DeleteUser(ctx, user.ID) })
+	if _, err := aCfg.db.SetUserRole(ctx, database.SetUserRoleParams{Role: role, Email: user.Email}); err != nil {
+		t.Fatal(err)
+	}
+	user.Role = role
+	return user
+}
+
+// serve sends a request with a JSON body through handler, signed in as user
+// if it isn't nil, and returns the recorded response.
+func serve(t *testing.T, aCfg *apiConfig, pattern string, handler http.HandlerFunc, method, path, body string, user *database.User) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	if user != nil {
+		token, err := auth.MakeJWT(user.ID, aCfg.secret, time.Hour)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc(pattern, handler)
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+	return rec
+}
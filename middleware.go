@@ -0,0 +1,167 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/anton-jj/chripy/internal/auth"
+	"github.com/anton-jj/chripy/internal/database"
+	"github.com/google/uuid"
+)
+
+type contextKey string
+
+const principalKey contextKey = "principal"
+
+// principal is whoever a request authenticated as. Scopes is nil for a normal
+// session (JWT), which may do anything the user may do, and holds the granted
+// scopes when the request used an API key or an OAuth access token.
+type principal struct {
+	UserID   uuid.UUID
+	APIKeyID uuid.UUID
+	ClientID uuid.UUID
+	Scopes   []string
+}
+
+func (p principal) isSession() bool {
+	return p.Scopes == nil
+}
+
+func (p principal) hasScope(scope string) bool {
+	if p.isSession() {
+		return true
+	}
+	return scope != "" && slices.Contains(p.Scopes, scope)
+}
+
+// middlewareRequireAuth rejects the request with 401 unless it carries a valid
+// access token or API key. An API key also needs the given scope, otherwise the
+// request gets a 403; an empty scope admits sessions only. Handlers behind it
+// can read the caller with userIDFromContext. Failing an ownership or
+// permission check after that is the handler's job and is answered with 403.
+func (aCfg *apiConfig) middlewareRequireAuth(scope string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		p, err := aCfg.authenticate(r)
+		if err != nil {
+			respondWithError(w, 401, "Unauthorized")
+			return
+		}
+		if !p.hasScope(scope) {
+			respondWithError(w, 403, "Forbidden")
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), principalKey, p)))
+	}
+}
+
+// middlewareOptionalAuth lets anonymous requests through untouched, but a
+// request that does send credentials must send valid ones.
+func (aCfg *apiConfig) middlewareOptionalAuth(scope string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		aCfg.middlewareRequireAuth(scope, next)(w, r)
+	}
+}
+
+var errSuspended = errors.New("account suspended")
+
+// authenticate resolves the request's credentials and makes sure the account
+// behind them is still allowed in, so suspending a user locks out their API
+// keys and unexpired access tokens too.
+func (aCfg *apiConfig) authenticate(r *http.Request) (principal, error) {
+	p, err := aCfg.authenticateCredentials(r)
+	if err != nil {
+		return principal{}, err
+	}
+	user, err := aCfg.db.GetUserById(r.Context(), p.UserID)
+	if err != nil {
+		return principal{}, err
+	}
+	if user.SuspendedAt.Valid {
+		return principal{}, errSuspended
+	}
+	return p, nil
+}
+
+func (aCfg *apiConfig) authenticateCredentials(r *http.Request) (principal, error) {
+	if strings.HasPrefix(strings.ToLower(r.Header.Get("Authorization")), "apikey ") {
+		return aCfg.authenticateAPIKey(r)
+	}
+	tok, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		return principal{}, err
+	}
+	userID, err := auth.ValidateJWT(tok, aCfg.secret)
+	if err == nil {
+		return principal{UserID: userID}, nil
+	}
+	return aCfg.authenticateAccessToken(r, tok)
+}
+
+func (aCfg *apiConfig) authenticateAccessToken(r *http.Request, tok string) (principal, error) {
+	claims, err := auth.ValidateAccessToken(tok, aCfg.secret)
+	if err != nil {
+		return principal{}, err
+	}
+	tokenID, err := uuid.Parse(claims.ID)
+	if err != nil {
+		return principal{}, err
+	}
+	row, err := aCfg.db.GetOAuthAccessToken(r.Context(), tokenID)
+	if err != nil {
+		return principal{}, err
+	}
+	if row.RevokedAt.Valid {
+		return principal{}, errors.New("access token revoked")
+	}
+	scopes := row.Scopes
+	if scopes == nil {
+		scopes = []string{}
+	}
+	return principal{UserID: row.UserID, ClientID: row.ClientID, Scopes: scopes}, nil
+}
+
+func (aCfg *apiConfig) authenticateAPIKey(r *http.Request) (principal, error) {
+	key, err := auth.GetAPIKey(r.Header)
+	if err != nil {
+		return principal{}, err
+	}
+	row, err := aCfg.db.GetAPIKeyByHash(r.Context(), auth.HashToken(key))
+	if err != nil {
+		return principal{}, err
+	}
+	now := time.Now().UTC()
+	if row.ExpiresAt.Valid && row.ExpiresAt.Time.Before(now) {
+		return principal{}, errors.New("api key expired")
+	}
+	err = aCfg.db.UpdateAPIKeyLastUsed(r.Context(), database.UpdateAPIKeyLastUsedParams{
+		LastUsedAt: sql.NullTime{Valid: true, Time: now},
+		ID:         row.ID,
+	})
+	if err != nil {
+		return principal{}, err
+	}
+	scopes := row.Scopes
+	if scopes == nil {
+		scopes = []string{}
+	}
+	return principal{UserID: row.UserID, APIKeyID: row.ID, Scopes: scopes}, nil
+}
+
+func principalFromContext(ctx context.Context) (principal, bool) {
+	p, ok := ctx.Value(principalKey).(principal)
+	return p, ok
+}
+
+func userIDFromContext(ctx context.Context) (uuid.UUID, bool) {
+	p, ok := principalFromContext(ctx)
+	return p.UserID, ok
+}
@@ -2,9 +2,16 @@ package main
 
 import (
 	"net/http"
+
+	"github.com/anton-jj/chripy/internal/audit"
+	"github.com/google/uuid"
 )
 
 func (aCfg *apiConfig) handleReset(w http.ResponseWriter, r *http.Request) {
+	if !aCfg.devMode {
+		respondWithError(w, 403, "reset is only allowed in the dev environment")
+		return
+	}
 	w.Header().Set("Content-Type", "text/plain; charset=UTF-8")
 	err := aCfg.db.ResetDatabase(r.Context())
 	if err != nil {
@@ -12,6 +19,7 @@ func (aCfg *apiConfig) handleReset(w http.ResponseWriter, r *http.Request) {
 		return
 
 	}
+	aCfg.recordAdminAudit(r, audit.ActionAdminReset, uuid.Nil, nil)
 	w.WriteHeader(http.StatusOK)
 	aCfg.fileServerHits.Store(0)
 	w.Write([]byte("hits reset to 0"))
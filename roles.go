@@ -0,0 +1,59 @@
+package main
+
+import (
+	"net/http"
+	"slices"
+)
+
+const (
+	roleUser      = "user"
+	roleModerator = "moderator"
+	roleAdmin     = "admin"
+)
+
+type permission string
+
+const (
+	permViewMetrics   permission = "metrics:view"
+	permResetDatabase permission = "database:reset"
+	permModerate      permission = "content:moderate"
+	permManageUsers   permission = "users:manage"
+	permViewAudit     permission = "audit:view"
+)
+
+// rolePermissions lists what each role may do. Roles are not hierarchical in
+// code; an admin simply has every permission listed.
+var rolePermissions = map[string][]permission{
+	roleUser:      {},
+	roleModerator: {permViewMetrics, permModerate},
+	roleAdmin:     {permViewMetrics, permResetDatabase, permModerate, permManageUsers, permViewAudit},
+}
+
+func roleHas(role string, perm permission) bool {
+	return slices.Contains(rolePermissions[role], perm)
+}
+
+// isStaff reports whether role grants any staff permission at all.
+func isStaff(role string) bool {
+	return len(rolePermissions[role]) > 0
+}
+
+// middlewareRequirePermission admits only sessions whose user's role grants
+// perm. API keys and OAuth tokens are never enough for staff endpoints. The
+// role is read from the database on every request so a demotion takes effect
+// immediately.
+func (aCfg *apiConfig) middlewareRequirePermission(perm permission, next http.HandlerFunc) http.HandlerFunc {
+	return aCfg.middlewareRequireAuth("", func(w http.ResponseWriter, r *http.Request) {
+		userID, _ := userIDFromContext(r.Context())
+		user, err := aCfg.db.GetUserById(r.Context(), userID)
+		if err != nil {
+			respondWithError(w, 401, "Unauthorized")
+			return
+		}
+		if !roleHas(user.Role, perm) {
+			respondWithError(w, 403, "Forbidden")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
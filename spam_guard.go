@@ -0,0 +1,79 @@
+package main
+
+import (
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/anton-jj/chripy/internal/database"
+	"github.com/anton-jj/chripy/internal/spam"
+)
+
+// spamDuplicateWindow is how far back identical chirps from other accounts
+// count against a new one.
+const spamDuplicateWindow = 24 * time.Hour
+
+// newSpamChecker builds the spam checker with the built in heuristic. The
+// thresholds can be tuned with SPAM_HOLD_SCORE and SPAM_REJECT_SCORE, and the
+// throttle with NEW_ACCOUNT_CHIRPS_PER_HOUR and CHIRPS_PER_HOUR.
+func newSpamChecker() *spam.Checker {
+	thresholds := spam.DefaultThresholds
+	thresholds.Hold = envFloat("SPAM_HOLD_SCORE", thresholds.Hold)
+	thresholds.Reject = envFloat("SPAM_REJECT_SCORE", thresholds.Reject)
+
+	throttle := spam.DefaultThrottle
+	throttle.NewAccountPerHour = envInt("NEW_ACCOUNT_CHIRPS_PER_HOUR", throttle.NewAccountPerHour)
+	throttle.PerHour = envInt("CHIRPS_PER_HOUR", throttle.PerHour)
+
+	return &spam.Checker{
+		Classifier: spam.Heuristic{},
+		Thresholds: thresholds,
+		Throttle:   throttle,
+	}
+}
+
+// checkSpam gathers the signals for a chirp user is about to post and asks
+// the spam checker what to do with it. When the signals can't be read the
+// chirp is held for review.
+func (aCfg *apiConfig) checkSpam(r *http.Request, user database.User, body string) spam.Decision {
+	now := time.Now().UTC()
+	recent, err := aCfg.db.CountChirpsByUserSince(r.Context(), database.CountChirpsByUserSinceParams{
+		UserID:    user.ID,
+		CreatedAt: now.Add(-time.Hour),
+	})
+	if err != nil {
+		log.Printf("spam: failed to count recent chirps: %v", err)
+		return spam.Decision{Verdict: spam.VerdictHold}
+	}
+	duplicates, err := aCfg.db.CountDuplicateAuthors(r.Context(), database.CountDuplicateAuthorsParams{
+		Body:   body,
+		UserID: user.ID,
+		Since:  now.Add(-spamDuplicateWindow),
+	})
+	if err != nil {
+		log.Printf("spam: failed to count duplicate chirps: %v", err)
+		return spam.Decision{Verdict: spam.VerdictHold}
+	}
+
+	return aCfg.spamChecker.Check(r.Context(), spam.Signals{
+		Body:             body,
+		AccountAge:       now.Sub(user.CreatedAt),
+		RecentPosts:      int(recent),
+		DuplicateAuthors: int(duplicates),
+	})
+}
+
+func envFloat(name string, fallback float64) float64 {
+	v := os.Getenv(name)
+	if v == "" {
+		return fallback
+	}
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil || f < 0 {
+		log.Printf("ignoring invalid %s=%q", name, v)
+		return fallback
+	}
+	return f
+}
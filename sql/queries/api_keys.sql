@@ -0,0 +1,18 @@
+-- name: CreateAPIKey :one
+INSERT INTO api_keys (id, created_at, updated_at, user_id, name, key_hash, scopes, expires_at)
+VALUES (gen_random_uuid(), NOW(), NOW(), $1, $2, $3, $4, $5) RETURNING *;
+
+-- name: GetAPIKeyByHash :one
+	SELECT * FROM api_keys WHERE key_hash = $1;
+
+-- name: GetAPIKeysByUser :many
+	SELECT * FROM api_keys WHERE user_id = $1 ORDER BY created_at ASC;
+
+-- name: UpdateAPIKeyLastUsed :exec
+	UPDATE api_keys SET last_used_at = $1 WHERE id = $2;
+
+-- name: DeleteAPIKey :exec
+	DELETE FROM api_keys WHERE id = $1 AND user_id = $2;
+
+-- name: DeleteAPIKeysForUser :exec
+	DELETE FROM api_keys WHERE user_id = $1;
@@ -0,0 +1,13 @@
+-- name: CreateAuditEvent :exec
+INSERT INTO audit_events (id, created_at, action, actor_id, target_id, ip, user_agent, metadata)
+VALUES (gen_random_uuid(), $1, $2, $3, $4, $5, $6, $7);
+
+-- name: ListAuditEvents :many
+	SELECT * FROM audit_events
+	WHERE (sqlc.arg(action)::text = '' OR action = sqlc.arg(action)::text)
+	AND (sqlc.narg(actor_id)::uuid IS NULL OR actor_id = sqlc.narg(actor_id)::uuid)
+	AND (sqlc.narg(target_id)::uuid IS NULL OR target_id = sqlc.narg(target_id)::uuid)
+	AND (sqlc.narg(since)::timestamp IS NULL OR created_at >= sqlc.narg(since)::timestamp)
+	AND (sqlc.narg(until)::timestamp IS NULL OR created_at < sqlc.narg(until)::timestamp)
+	ORDER BY created_at DESC
+	LIMIT sqlc.arg(max_results) OFFSET sqlc.arg(skip);
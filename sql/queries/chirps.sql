@@ -1,10 +1,46 @@
 -- name: CreateChirp :one
-INSERT INTO chirps (id, created_at, updated_at, body, user_id)
-VALUES (gen_random_uuid(), NOW(), NOW(), $1, $2) RETURNING *;
+INSERT INTO chirps (id, created_at, updated_at, body, user_id, needs_review, review_reason, hidden_at, content_warning, sensitive)
+VALUES (gen_random_uuid(), NOW(), NOW(), $1, $2, $3, $4, $5, $6, $7) RETURNING *;
 
 -- name: GetAllChirps :many
-SELECT * FROM chirps ORDER BY created_at ASC;
+SELECT * FROM chirps c
+WHERE c.hidden_at IS NULL
+AND NOT EXISTS (SELECT 1 FROM users u WHERE u.id = c.user_id AND u.limited_at IS NOT NULL)
+ORDER BY c.created_at ASC;
 -- name: GetChirpById :one
 SELECT * FROM chirps WHERE ID = $1;
 -- name: DeleteChirpById :exec
  DELETE FROM chirps WHERE ID = $1;
+-- name: GetChirpsByUser :many
+SELECT * FROM chirps WHERE user_id = $1 ORDER BY created_at ASC;
+-- name: HideChirp :exec
+UPDATE chirps SET hidden_at = NOW(), updated_at = NOW() WHERE id = $1;
+-- name: SetChirpSensitivity :one
+UPDATE chirps SET content_warning = $1, sensitive = $2, updated_at = NOW() WHERE id = $3 RETURNING *;
+-- name: UnhideChirp :exec
+UPDATE chirps SET hidden_at = NULL, needs_review = false, updated_at = NOW() WHERE id = $1;
+-- name: CountChirpsByUserSince :one
+SELECT COUNT(*) FROM chirps WHERE user_id = $1 AND created_at >= $2;
+-- name: CountDuplicateAuthors :one
+SELECT COUNT(DISTINCT user_id) FROM chirps
+WHERE md5(lower(body)) = md5(lower(sqlc.arg(body)::text))
+AND user_id <> sqlc.arg(user_id)::uuid
+AND created_at >= sqlc.arg(since)::timestamp;
+-- name: GetChirpsForViewer :many
+SELECT * FROM chirps c
+WHERE (
+	c.user_id = sqlc.arg(viewer_id)::uuid
+	OR (
+		c.hidden_at IS NULL
+		AND NOT EXISTS (SELECT 1 FROM users u WHERE u.id = c.user_id AND u.limited_at IS NOT NULL)
+	)
+)
+AND NOT EXISTS (
+	SELECT 1 FROM user_blocks b
+	WHERE (b.blocker_id = sqlc.arg(viewer_id)::uuid AND b.blocked_id = c.user_id)
+	OR (b.blocker_id = c.user_id AND b.blocked_id = sqlc.arg(viewer_id)::uuid)
+)
+AND NOT EXISTS (
+	SELECT 1 FROM user_mutes m WHERE m.muter_id = sqlc.arg(viewer_id)::uuid AND m.muted_id = c.user_id
+)
+ORDER BY c.created_at ASC;
@@ -0,0 +1,22 @@
+-- name: CreateKeywordFilter :one
+INSERT INTO keyword_filters (id, created_at, updated_at, user_id, phrase, whole_word, contexts, expires_at)
+VALUES (gen_random_uuid(), NOW(), NOW(), $1, $2, $3, $4, $5) RETURNING *;
+
+-- name: ListKeywordFilters :many
+	SELECT * FROM keyword_filters WHERE user_id = $1 ORDER BY created_at ASC;
+
+-- name: GetActiveKeywordFilters :many
+	SELECT * FROM keyword_filters
+	WHERE user_id = $1 AND (expires_at IS NULL OR expires_at > NOW());
+
+-- name: CountKeywordFilters :one
+	SELECT COUNT(*) FROM keyword_filters WHERE user_id = $1;
+
+-- name: UpdateKeywordFilter :one
+UPDATE keyword_filters
+SET phrase = $1, whole_word = $2, contexts = $3, expires_at = $4, updated_at = NOW()
+WHERE id = $5 AND user_id = $6
+RETURNING *;
+
+-- name: DeleteKeywordFilter :execrows
+	DELETE FROM keyword_filters WHERE id = $1 AND user_id = $2;
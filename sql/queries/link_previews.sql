@@ -0,0 +1,26 @@
+-- name: EnsureLinkPreview :exec
+INSERT INTO link_previews (url, created_at)
+VALUES ($1, NOW()) ON CONFLICT DO NOTHING;
+
+-- name: CreateChirpLink :exec
+INSERT INTO chirp_links (chirp_id, position, url, start_index, end_index)
+VALUES ($1, $2, $3, $4, $5);
+
+-- name: ListPendingLinkPreviews :many
+	SELECT url FROM link_previews WHERE status = 'pending' ORDER BY created_at ASC LIMIT $1;
+
+-- name: CompleteLinkPreview :exec
+UPDATE link_previews
+SET status = 'ready', title = $1, description = $2, image_url = $3, site_name = $4, error = '', fetched_at = NOW()
+WHERE url = $5;
+
+-- name: FailLinkPreview :exec
+UPDATE link_previews SET status = 'failed', error = $1, fetched_at = NOW() WHERE url = $2;
+
+-- name: GetChirpLinks :many
+SELECT cl.chirp_id, cl.position, cl.url, cl.start_index, cl.end_index,
+	lp.status, lp.title, lp.description, lp.image_url, lp.site_name
+FROM chirp_links cl
+JOIN link_previews lp ON lp.url = cl.url
+WHERE cl.chirp_id = ANY(sqlc.arg(chirp_ids)::uuid[])
+ORDER BY cl.chirp_id, cl.position;
@@ -0,0 +1,17 @@
+-- name: GetLoginAttempts :one
+	SELECT * FROM login_attempts WHERE attempt_key = $1;
+
+-- name: RecordLoginFailure :one
+INSERT INTO login_attempts (attempt_key, failures, last_failure_at)
+VALUES (sqlc.arg(attempt_key), 1, sqlc.arg(failed_at))
+ON CONFLICT (attempt_key) DO UPDATE SET
+	failures = CASE WHEN login_attempts.last_failure_at < sqlc.arg(window_start) THEN 1 ELSE login_attempts.failures + 1 END,
+	locked_until = CASE WHEN login_attempts.last_failure_at < sqlc.arg(window_start) THEN NULL ELSE login_attempts.locked_until END,
+	last_failure_at = sqlc.arg(failed_at)
+RETURNING *;
+
+-- name: LockLoginAttempts :exec
+	UPDATE login_attempts SET locked_until = $1 WHERE attempt_key = $2;
+
+-- name: ResetLoginAttempts :exec
+	DELETE FROM login_attempts WHERE attempt_key = $1;
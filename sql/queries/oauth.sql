@@ -0,0 +1,26 @@
+-- name: CreateOAuthClient :one
+INSERT INTO oauth_clients (id, created_at, updated_at, owner_id, name, secret_hash, redirect_uris)
+VALUES (gen_random_uuid(), NOW(), NOW(), $1, $2, $3, $4) RETURNING *;
+
+-- name: GetOAuthClient :one
+	SELECT * FROM oauth_clients WHERE id = $1;
+
+-- name: CreateOAuthCode :exec
+INSERT INTO oauth_codes (code_hash, created_at, client_id, user_id, redirect_uri, scopes, code_challenge, expires_at)
+VALUES ($1, NOW(), $2, $3, $4, $5, $6, $7);
+
+-- name: ConsumeOAuthCode :one
+	UPDATE oauth_codes SET used_at = NOW() WHERE code_hash = $1 AND used_at IS NULL RETURNING *;
+
+-- name: CreateOAuthAccessToken :one
+INSERT INTO oauth_access_tokens (id, created_at, client_id, user_id, scopes, expires_at)
+VALUES (gen_random_uuid(), NOW(), $1, $2, $3, $4) RETURNING *;
+
+-- name: GetOAuthAccessToken :one
+	SELECT * FROM oauth_access_tokens WHERE id = $1;
+
+-- name: RevokeOAuthAccessToken :exec
+	UPDATE oauth_access_tokens SET revoked_at = NOW() WHERE id = $1 AND client_id = $2;
+
+-- name: RevokeAllOAuthAccessTokensForUser :exec
+	UPDATE oauth_access_tokens SET revoked_at = NOW() WHERE user_id = $1 AND revoked_at IS NULL;
@@ -0,0 +1,13 @@
+-- name: CreatePasswordResetToken :exec
+INSERT INTO password_reset_tokens (token_hash, created_at, user_id, expires_at)
+VALUES ($1, NOW(), $2, $3);
+
+-- name: ConsumePasswordResetToken :one
+	UPDATE password_reset_tokens SET used_at = NOW() WHERE token_hash = $1 AND used_at IS NULL AND expires_at > NOW() RETURNING *;
+
+-- name: InvalidatePasswordResetTokens :exec
+	UPDATE password_reset_tokens SET used_at = NOW() WHERE user_id = $1 AND used_at IS NULL;
+
+-- name: GetUserByPasswordResetToken :one
+	SELECT u.* FROM users u JOIN password_reset_tokens t ON u.id = t.user_id
+	WHERE t.token_hash = $1 AND t.used_at IS NULL AND t.expires_at > NOW();
@@ -0,0 +1,16 @@
+-- name: AddReaction :execrows
+INSERT INTO chirp_reactions (chirp_id, user_id, emoji, created_at)
+VALUES ($1, $2, $3, NOW()) ON CONFLICT DO NOTHING;
+
+-- name: RemoveReaction :execrows
+	DELETE FROM chirp_reactions WHERE chirp_id = $1 AND user_id = $2 AND emoji = $3;
+
+-- name: GetReactionCounts :many
+SELECT chirp_id, emoji, count FROM chirp_reaction_counts
+WHERE chirp_id = ANY(sqlc.arg(chirp_ids)::uuid[])
+ORDER BY chirp_id, count DESC, emoji;
+
+-- name: GetViewerReactions :many
+SELECT chirp_id, emoji FROM chirp_reactions
+WHERE user_id = sqlc.arg(user_id)::uuid AND chirp_id = ANY(sqlc.arg(chirp_ids)::uuid[])
+ORDER BY chirp_id, created_at;
@@ -0,0 +1,26 @@
+-- name: BlockUser :exec
+INSERT INTO user_blocks (blocker_id, blocked_id, created_at)
+VALUES ($1, $2, NOW()) ON CONFLICT DO NOTHING;
+
+-- name: UnblockUser :exec
+	DELETE FROM user_blocks WHERE blocker_id = $1 AND blocked_id = $2;
+
+-- name: ListBlockedUsers :many
+	SELECT * FROM user_blocks WHERE blocker_id = $1 ORDER BY created_at DESC;
+
+-- name: IsBlockedEitherWay :one
+	SELECT EXISTS (
+		SELECT 1 FROM user_blocks
+		WHERE (blocker_id = sqlc.arg(user_a)::uuid AND blocked_id = sqlc.arg(user_b)::uuid)
+		OR (blocker_id = sqlc.arg(user_b)::uuid AND blocked_id = sqlc.arg(user_a)::uuid)
+	);
+
+-- name: MuteUser :exec
+INSERT INTO user_mutes (muter_id, muted_id, created_at)
+VALUES ($1, $2, NOW()) ON CONFLICT DO NOTHING;
+
+-- name: UnmuteUser :exec
+	DELETE FROM user_mutes WHERE muter_id = $1 AND muted_id = $2;
+
+-- name: ListMutedUsers :many
+	SELECT * FROM user_mutes WHERE muter_id = $1 ORDER BY created_at DESC;
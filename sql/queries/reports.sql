@@ -0,0 +1,20 @@
+-- name: CreateReport :one
+INSERT INTO reports (id, created_at, updated_at, reporter_id, reported_user_id, chirp_id, chirp_body, reason, details, kind)
+VALUES (gen_random_uuid(), NOW(), NOW(), $1, $2, $3, $4, $5, $6, $7) RETURNING *;
+
+-- name: GetReport :one
+	SELECT * FROM reports WHERE id = $1;
+
+-- name: ListReports :many
+	SELECT * FROM reports
+	WHERE sqlc.arg(status)::text = '' OR status = sqlc.arg(status)::text
+	ORDER BY created_at ASC
+	LIMIT sqlc.arg(max_results) OFFSET sqlc.arg(skip);
+
+-- name: ResolveReport :one
+	UPDATE reports SET status = 'resolved', resolution = $1, moderator_id = $2, moderator_notes = $3, resolved_at = NOW(), updated_at = NOW()
+	WHERE id = $4 AND status = 'open' RETURNING *;
+
+-- name: ReopenReport :exec
+	UPDATE reports SET status = 'open', resolution = '', moderator_id = NULL, moderator_notes = '', resolved_at = NULL, updated_at = NOW()
+	WHERE id = $1;
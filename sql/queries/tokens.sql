@@ -6,3 +6,7 @@ VALUES ($1, NOW(), NOW(), $2, $3, $4) RETURNING *;
 	SELECT * FROM refresh_tokens WHERE token = $1;
 -- name: UpdateRevokedAt :exec
 	UPDATE refresh_tokens SET revoked_at = $1, updated_at = $2 WHERE token = $3; 
+-- name: RevokeAllRefreshTokensForUser :exec
+	UPDATE refresh_tokens SET revoked_at = NOW(), updated_at = NOW() WHERE user_id = $1 AND revoked_at IS NULL;
+-- name: GetRefreshTokensByUser :many
+	SELECT * FROM refresh_tokens WHERE user_id = $1 ORDER BY created_at ASC;
@@ -14,5 +14,73 @@ DELETE from users;
 -- name: GetUserFromRefreshToken :one
 	SELECT u.* FROM users u JOIN refresh_tokens rt ON u.id = rt.user_id WHERE rt.token = $1;
 
--- name: UpdateUser :exec
-	UPDATE users SET email = $1, hashed_password = $2 WHERE id = $3; 
+-- name: SetPendingEmail :exec
+	UPDATE users SET pending_email = $1, updated_at = NOW() WHERE id = $2;
+
+-- name: ConfirmEmailChange :execrows
+	UPDATE users SET email = pending_email, pending_email = NULL, email_verified_at = NOW(), updated_at = NOW() WHERE id = $1 AND pending_email = $2;
+
+-- name: SetTOTPSecret :exec
+	UPDATE users SET totp_secret = $1, totp_enabled_at = NULL, updated_at = NOW() WHERE id = $2;
+
+-- name: EnableTOTP :exec
+	UPDATE users SET totp_enabled_at = NOW(), updated_at = NOW() WHERE id = $1;
+
+-- name: DisableTOTP :exec
+	UPDATE users SET totp_secret = NULL, totp_enabled_at = NULL, totp_last_step = NULL, updated_at = NOW() WHERE id = $1;
+
+-- name: UpdateTOTPLastStep :execrows
+	UPDATE users SET totp_last_step = $1 WHERE id = $2 AND (totp_last_step IS NULL OR totp_last_step < $1);
+
+-- name: VerifyUserEmail :execrows
+	UPDATE users SET email_verified_at = NOW(), updated_at = NOW() WHERE id = $1 AND email = $2;
+
+-- name: UpdateUserPassword :exec
+	UPDATE users SET hashed_password = $1, must_reset_password = false, updated_at = NOW() WHERE id = $2;
+
+-- name: RehashUserPassword :execrows
+	UPDATE users SET hashed_password = sqlc.arg(new_hash)::text
+	WHERE id = sqlc.arg(id)::uuid AND hashed_password = sqlc.arg(old_hash)::text;
+
+-- name: ScheduleUserDeletion :exec
+	UPDATE users SET delete_after = $1, updated_at = NOW() WHERE id = $2;
+
+-- name: CancelUserDeletion :execrows
+	UPDATE users SET delete_after = NULL, updated_at = NOW() WHERE id = $1 AND delete_after IS NOT NULL;
+
+-- name: DeleteUser :exec
+	DELETE FROM users WHERE id = $1;
+
+-- name: DeleteExpiredUsers :execrows
+	DELETE FROM users WHERE delete_after IS NOT NULL AND delete_after <= NOW();
+
+-- name: SetUserRole :execrows
+	UPDATE users SET role = $1, updated_at = NOW() WHERE email = $2;
+
+-- name: ListUsers :many
+	SELECT * FROM users
+	WHERE sqlc.arg(search)::text = '' OR email ILIKE '%' || sqlc.arg(search)::text || '%'
+	ORDER BY created_at ASC, id ASC
+	LIMIT sqlc.arg(max_results) OFFSET sqlc.arg(skip);
+
+-- name: CountUsers :one
+	SELECT COUNT(*) FROM users
+	WHERE sqlc.arg(search)::text = '' OR email ILIKE '%' || sqlc.arg(search)::text || '%';
+
+-- name: SuspendUser :exec
+	UPDATE users SET suspended_at = NOW(), suspension_reason = $1, updated_at = NOW() WHERE id = $2;
+
+-- name: UnsuspendUser :exec
+	UPDATE users SET suspended_at = NULL, suspension_reason = '', updated_at = NOW() WHERE id = $1;
+
+-- name: LimitUser :exec
+	UPDATE users SET limited_at = NOW(), limit_reason = $1, updated_at = NOW() WHERE id = $2;
+
+-- name: UnlimitUser :exec
+	UPDATE users SET limited_at = NULL, limit_reason = '', updated_at = NOW() WHERE id = $1;
+
+-- name: SetSensitiveContentPreference :exec
+	UPDATE users SET sensitive_content = $1, updated_at = NOW() WHERE id = $2;
+
+-- name: RequirePasswordReset :exec
+	UPDATE users SET must_reset_password = true, updated_at = NOW() WHERE id = $1;
@@ -0,0 +1,22 @@
+-- name: CreateWebAuthnCredential :one
+INSERT INTO webauthn_credentials (id, created_at, user_id, name, credential_id, public_key, sign_count)
+VALUES (gen_random_uuid(), NOW(), $1, $2, $3, $4, $5) RETURNING *;
+
+-- name: GetWebAuthnCredential :one
+	SELECT * FROM webauthn_credentials WHERE credential_id = $1;
+
+-- name: GetWebAuthnCredentialsByUser :many
+	SELECT * FROM webauthn_credentials WHERE user_id = $1 ORDER BY created_at ASC;
+
+-- name: UpdateWebAuthnSignCount :exec
+	UPDATE webauthn_credentials SET sign_count = $1, last_used_at = NOW() WHERE id = $2;
+
+-- name: DeleteWebAuthnCredential :exec
+	DELETE FROM webauthn_credentials WHERE id = $1 AND user_id = $2;
+
+-- name: CreateWebAuthnChallenge :exec
+INSERT INTO webauthn_challenges (challenge, created_at, user_id, ceremony, expires_at)
+VALUES ($1, NOW(), $2, $3, $4);
+
+-- name: ConsumeWebAuthnChallenge :one
+	DELETE FROM webauthn_challenges WHERE challenge = $1 AND ceremony = $2 RETURNING *;
@@ -0,0 +1,16 @@
+-- +goose Up
+	CREATE TABLE api_keys (
+		id UUID PRIMARY KEY,
+		created_at TIMESTAMP NOT NULL,
+		updated_at TIMESTAMP NOT NULL,
+		user_id UUID NOT NULL,
+		FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+		name TEXT NOT NULL,
+		key_hash TEXT UNIQUE NOT NULL,
+		scopes TEXT[] NOT NULL,
+		expires_at TIMESTAMP,
+		last_used_at TIMESTAMP
+	);
+
+-- +goose Down
+	 DROP TABLE IF EXISTS api_keys;
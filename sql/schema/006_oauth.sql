@@ -0,0 +1,42 @@
+-- +goose Up
+	CREATE TABLE oauth_clients (
+		id UUID PRIMARY KEY,
+		created_at TIMESTAMP NOT NULL,
+		updated_at TIMESTAMP NOT NULL,
+		owner_id UUID NOT NULL,
+		FOREIGN KEY (owner_id) REFERENCES users(id) ON DELETE CASCADE,
+		name TEXT NOT NULL,
+		secret_hash TEXT,
+		redirect_uris TEXT[] NOT NULL
+	);
+
+	CREATE TABLE oauth_codes (
+		code_hash TEXT PRIMARY KEY,
+		created_at TIMESTAMP NOT NULL,
+		client_id UUID NOT NULL,
+		FOREIGN KEY (client_id) REFERENCES oauth_clients(id) ON DELETE CASCADE,
+		user_id UUID NOT NULL,
+		FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+		redirect_uri TEXT NOT NULL,
+		scopes TEXT[] NOT NULL,
+		code_challenge TEXT NOT NULL,
+		expires_at TIMESTAMP NOT NULL,
+		used_at TIMESTAMP
+	);
+
+	CREATE TABLE oauth_access_tokens (
+		id UUID PRIMARY KEY,
+		created_at TIMESTAMP NOT NULL,
+		client_id UUID NOT NULL,
+		FOREIGN KEY (client_id) REFERENCES oauth_clients(id) ON DELETE CASCADE,
+		user_id UUID NOT NULL,
+		FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+		scopes TEXT[] NOT NULL,
+		expires_at TIMESTAMP NOT NULL,
+		revoked_at TIMESTAMP
+	);
+
+-- +goose Down
+	 DROP TABLE IF EXISTS oauth_access_tokens;
+	 DROP TABLE IF EXISTS oauth_codes;
+	 DROP TABLE IF EXISTS oauth_clients;
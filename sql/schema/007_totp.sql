@@ -0,0 +1,24 @@
+-- +goose Up
+ALTER TABLE users ADD totp_secret TEXT;
+
+ALTER TABLE users ADD totp_enabled_at TIMESTAMP;
+
+ALTER TABLE users ADD totp_last_step BIGINT;
+
+CREATE TABLE recovery_codes (
+	id UUID PRIMARY KEY,
+	created_at TIMESTAMP NOT NULL,
+	user_id UUID NOT NULL,
+	FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+	code_hash TEXT NOT NULL,
+	used_at TIMESTAMP
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS recovery_codes;
+
+ALTER TABLE users DROP COLUMN IF EXISTS totp_last_step;
+
+ALTER TABLE users DROP COLUMN IF EXISTS totp_enabled_at;
+
+ALTER TABLE users DROP COLUMN IF EXISTS totp_secret;
@@ -0,0 +1,25 @@
+-- +goose Up
+	CREATE TABLE webauthn_credentials (
+		id UUID PRIMARY KEY,
+		created_at TIMESTAMP NOT NULL,
+		user_id UUID NOT NULL,
+		FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+		name TEXT NOT NULL,
+		credential_id BYTEA UNIQUE NOT NULL,
+		public_key BYTEA NOT NULL,
+		sign_count BIGINT NOT NULL,
+		last_used_at TIMESTAMP
+	);
+
+	CREATE TABLE webauthn_challenges (
+		challenge BYTEA PRIMARY KEY,
+		created_at TIMESTAMP NOT NULL,
+		user_id UUID,
+		FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+		ceremony TEXT NOT NULL,
+		expires_at TIMESTAMP NOT NULL
+	);
+
+-- +goose Down
+	 DROP TABLE IF EXISTS webauthn_challenges;
+	 DROP TABLE IF EXISTS webauthn_credentials;
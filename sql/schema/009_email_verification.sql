@@ -0,0 +1,5 @@
+-- +goose Up
+ALTER TABLE users ADD email_verified_at TIMESTAMP;
+
+-- +goose Down
+ALTER TABLE users DROP COLUMN IF EXISTS email_verified_at;
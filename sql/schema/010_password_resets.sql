@@ -0,0 +1,12 @@
+-- +goose Up
+	CREATE TABLE password_reset_tokens (
+		token_hash TEXT PRIMARY KEY,
+		created_at TIMESTAMP NOT NULL,
+		user_id UUID NOT NULL,
+		FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+		expires_at TIMESTAMP NOT NULL,
+		used_at TIMESTAMP
+	);
+
+-- +goose Down
+	 DROP TABLE IF EXISTS password_reset_tokens;
@@ -0,0 +1,5 @@
+-- +goose Up
+ALTER TABLE users ADD pending_email TEXT;
+
+-- +goose Down
+ALTER TABLE users DROP COLUMN IF EXISTS pending_email;
@@ -0,0 +1,5 @@
+-- +goose Up
+ALTER TABLE users ADD delete_after TIMESTAMP;
+
+-- +goose Down
+ALTER TABLE users DROP COLUMN IF EXISTS delete_after;
@@ -0,0 +1,9 @@
+-- +goose Up
+ALTER TABLE users ADD suspended_at TIMESTAMP;
+ALTER TABLE users ADD suspension_reason TEXT NOT NULL DEFAULT '';
+ALTER TABLE users ADD must_reset_password BOOLEAN NOT NULL DEFAULT false;
+
+-- +goose Down
+ALTER TABLE users DROP COLUMN IF EXISTS must_reset_password;
+ALTER TABLE users DROP COLUMN IF EXISTS suspension_reason;
+ALTER TABLE users DROP COLUMN IF EXISTS suspended_at;
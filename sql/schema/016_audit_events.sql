@@ -0,0 +1,33 @@
+-- +goose Up
+	CREATE TABLE audit_events (
+		id UUID PRIMARY KEY,
+		created_at TIMESTAMP NOT NULL,
+		action TEXT NOT NULL,
+		actor_id UUID,
+		target_id UUID,
+		ip TEXT NOT NULL,
+		user_agent TEXT NOT NULL,
+		metadata JSONB NOT NULL DEFAULT '{}'
+	);
+
+	CREATE INDEX audit_events_created_at_idx ON audit_events (created_at);
+	CREATE INDEX audit_events_actor_idx ON audit_events (actor_id, created_at);
+	CREATE INDEX audit_events_target_idx ON audit_events (target_id, created_at);
+
+-- The log outlives the users it mentions, so there are no foreign keys, and
+-- rows can be neither changed nor removed.
+-- +goose StatementBegin
+CREATE FUNCTION audit_events_append_only() RETURNS trigger AS $$
+BEGIN
+	RAISE EXCEPTION 'audit_events is append-only';
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+	CREATE TRIGGER audit_events_append_only
+		BEFORE UPDATE OR DELETE ON audit_events
+		FOR EACH ROW EXECUTE FUNCTION audit_events_append_only();
+
+-- +goose Down
+	 DROP TABLE IF EXISTS audit_events;
+	 DROP FUNCTION IF EXISTS audit_events_append_only();
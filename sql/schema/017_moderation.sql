@@ -0,0 +1,21 @@
+-- +goose Up
+	CREATE TABLE moderation_rules (
+		id UUID PRIMARY KEY,
+		created_at TIMESTAMP NOT NULL,
+		updated_at TIMESTAMP NOT NULL,
+		term TEXT UNIQUE NOT NULL,
+		action TEXT NOT NULL CHECK (action IN ('mask', 'flag', 'reject'))
+	);
+
+	INSERT INTO moderation_rules (id, created_at, updated_at, term, action) VALUES
+		(gen_random_uuid(), NOW(), NOW(), 'kerfuffle', 'mask'),
+		(gen_random_uuid(), NOW(), NOW(), 'sharbert', 'mask'),
+		(gen_random_uuid(), NOW(), NOW(), 'fornax', 'mask');
+
+	ALTER TABLE chirps ADD needs_review BOOLEAN NOT NULL DEFAULT false;
+	ALTER TABLE chirps ADD review_reason TEXT NOT NULL DEFAULT '';
+
+-- +goose Down
+	ALTER TABLE chirps DROP COLUMN IF EXISTS review_reason;
+	ALTER TABLE chirps DROP COLUMN IF EXISTS needs_review;
+	 DROP TABLE IF EXISTS moderation_rules;
@@ -0,0 +1,32 @@
+-- +goose Up
+	CREATE TABLE reports (
+		id UUID PRIMARY KEY,
+		created_at TIMESTAMP NOT NULL,
+		updated_at TIMESTAMP NOT NULL,
+		reporter_id UUID,
+		reported_user_id UUID NOT NULL,
+		chirp_id UUID,
+		chirp_body TEXT NOT NULL DEFAULT '',
+		reason TEXT NOT NULL CHECK (reason IN ('spam', 'harassment', 'hate', 'violence', 'sexual', 'self_harm', 'impersonation', 'other', 'automated')),
+		details TEXT NOT NULL DEFAULT '',
+		status TEXT NOT NULL DEFAULT 'open' CHECK (status IN ('open', 'resolved')),
+		resolution TEXT NOT NULL DEFAULT '',
+		moderator_id UUID,
+		moderator_notes TEXT NOT NULL DEFAULT '',
+		resolved_at TIMESTAMP,
+		FOREIGN KEY (reporter_id) REFERENCES users(id) ON DELETE SET NULL,
+		FOREIGN KEY (reported_user_id) REFERENCES users(id) ON DELETE CASCADE,
+		FOREIGN KEY (chirp_id) REFERENCES chirps(id) ON DELETE SET NULL,
+		FOREIGN KEY (moderator_id) REFERENCES users(id) ON DELETE SET NULL
+	);
+
+	CREATE INDEX reports_status_idx ON reports (status, created_at);
+	-- One open report per reporter and chirp (or user) is enough.
+	CREATE UNIQUE INDEX reports_open_chirp_idx ON reports (reporter_id, chirp_id) WHERE status = 'open' AND chirp_id IS NOT NULL;
+	CREATE UNIQUE INDEX reports_open_user_idx ON reports (reporter_id, reported_user_id) WHERE status = 'open' AND chirp_id IS NULL;
+
+	ALTER TABLE chirps ADD hidden_at TIMESTAMP;
+
+-- +goose Down
+	ALTER TABLE chirps DROP COLUMN IF EXISTS hidden_at;
+	 DROP TABLE IF EXISTS reports;
@@ -0,0 +1,7 @@
+-- +goose Up
+	CREATE INDEX chirps_user_created_at_idx ON chirps (user_id, created_at);
+	CREATE INDEX chirps_body_hash_idx ON chirps (md5(lower(body)), created_at);
+
+-- +goose Down
+	DROP INDEX IF EXISTS chirps_body_hash_idx;
+	DROP INDEX IF EXISTS chirps_user_created_at_idx;
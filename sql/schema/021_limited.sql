@@ -0,0 +1,7 @@
+-- +goose Up
+ALTER TABLE users ADD limited_at TIMESTAMP;
+ALTER TABLE users ADD limit_reason TEXT NOT NULL DEFAULT '';
+
+-- +goose Down
+ALTER TABLE users DROP COLUMN IF EXISTS limit_reason;
+ALTER TABLE users DROP COLUMN IF EXISTS limited_at;
@@ -0,0 +1,10 @@
+-- +goose Up
+ALTER TABLE chirps ADD content_warning TEXT NOT NULL DEFAULT '';
+ALTER TABLE chirps ADD sensitive BOOLEAN NOT NULL DEFAULT false;
+ALTER TABLE users ADD sensitive_content TEXT NOT NULL DEFAULT 'collapse'
+	CHECK (sensitive_content IN ('collapse', 'expand', 'hide'));
+
+-- +goose Down
+ALTER TABLE users DROP COLUMN IF EXISTS sensitive_content;
+ALTER TABLE chirps DROP COLUMN IF EXISTS sensitive;
+ALTER TABLE chirps DROP COLUMN IF EXISTS content_warning;
@@ -0,0 +1,17 @@
+-- +goose Up
+	CREATE TABLE keyword_filters (
+		id UUID PRIMARY KEY,
+		created_at TIMESTAMP NOT NULL,
+		updated_at TIMESTAMP NOT NULL,
+		user_id UUID NOT NULL,
+		FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+		phrase TEXT NOT NULL,
+		whole_word BOOLEAN NOT NULL DEFAULT true,
+		contexts TEXT[] NOT NULL,
+		expires_at TIMESTAMP
+	);
+
+	CREATE INDEX keyword_filters_user_idx ON keyword_filters (user_id);
+
+-- +goose Down
+	 DROP TABLE IF EXISTS keyword_filters;
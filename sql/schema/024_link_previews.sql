@@ -0,0 +1,30 @@
+-- +goose Up
+	CREATE TABLE link_previews (
+		url TEXT PRIMARY KEY,
+		created_at TIMESTAMP NOT NULL,
+		status TEXT NOT NULL DEFAULT 'pending'
+			CHECK (status IN ('pending', 'ready', 'failed')),
+		title TEXT NOT NULL DEFAULT '',
+		description TEXT NOT NULL DEFAULT '',
+		image_url TEXT NOT NULL DEFAULT '',
+		site_name TEXT NOT NULL DEFAULT '',
+		error TEXT NOT NULL DEFAULT '',
+		fetched_at TIMESTAMP
+	);
+
+	CREATE INDEX link_previews_pending_idx ON link_previews (created_at) WHERE status = 'pending';
+
+	CREATE TABLE chirp_links (
+		chirp_id UUID NOT NULL,
+		position INTEGER NOT NULL,
+		url TEXT NOT NULL,
+		start_index INTEGER NOT NULL,
+		end_index INTEGER NOT NULL,
+		PRIMARY KEY (chirp_id, position),
+		FOREIGN KEY (chirp_id) REFERENCES chirps(id) ON DELETE CASCADE,
+		FOREIGN KEY (url) REFERENCES link_previews(url)
+	);
+
+-- +goose Down
+	 DROP TABLE IF EXISTS chirp_links;
+	 DROP TABLE IF EXISTS link_previews;
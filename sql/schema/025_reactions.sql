@@ -0,0 +1,49 @@
+-- +goose Up
+	CREATE TABLE chirp_reactions (
+		chirp_id UUID NOT NULL,
+		user_id UUID NOT NULL,
+		emoji TEXT NOT NULL,
+		created_at TIMESTAMP NOT NULL,
+		PRIMARY KEY (chirp_id, user_id, emoji),
+		FOREIGN KEY (chirp_id) REFERENCES chirps(id) ON DELETE CASCADE,
+		FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+	);
+
+	CREATE INDEX chirp_reactions_user_idx ON chirp_reactions (user_id);
+
+-- Per chirp totals, kept up to date by a trigger so chirp lists read one row
+-- per emoji instead of counting reactions.
+	CREATE TABLE chirp_reaction_counts (
+		chirp_id UUID NOT NULL,
+		emoji TEXT NOT NULL,
+		count INTEGER NOT NULL,
+		PRIMARY KEY (chirp_id, emoji),
+		FOREIGN KEY (chirp_id) REFERENCES chirps(id) ON DELETE CASCADE
+	);
+
+-- +goose StatementBegin
+CREATE FUNCTION chirp_reaction_counts_update() RETURNS trigger AS $$
+BEGIN
+	IF TG_OP = 'INSERT' THEN
+		INSERT INTO chirp_reaction_counts (chirp_id, emoji, count)
+		VALUES (NEW.chirp_id, NEW.emoji, 1)
+		ON CONFLICT (chirp_id, emoji) DO UPDATE SET count = chirp_reaction_counts.count + 1;
+		RETURN NEW;
+	END IF;
+	UPDATE chirp_reaction_counts SET count = count - 1
+	WHERE chirp_id = OLD.chirp_id AND emoji = OLD.emoji;
+	DELETE FROM chirp_reaction_counts
+	WHERE chirp_id = OLD.chirp_id AND emoji = OLD.emoji AND count <= 0;
+	RETURN OLD;
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+	CREATE TRIGGER chirp_reaction_counts_update
+		AFTER INSERT OR DELETE ON chirp_reactions
+		FOR EACH ROW EXECUTE FUNCTION chirp_reaction_counts_update();
+
+-- +goose Down
+	 DROP TABLE IF EXISTS chirp_reactions;
+	 DROP TABLE IF EXISTS chirp_reaction_counts;
+	 DROP FUNCTION IF EXISTS chirp_reaction_counts_update();
@@ -0,0 +1,13 @@
+-- +goose Up
+-- A chirp report keeps being one after its chirp is deleted and chirp_id is
+-- set to NULL, so it must not fall under the one-open-user-report index.
+ALTER TABLE reports ADD kind TEXT NOT NULL DEFAULT 'user' CHECK (kind IN ('user', 'chirp'));
+UPDATE reports SET kind = 'chirp' WHERE chirp_id IS NOT NULL OR chirp_body <> '';
+
+DROP INDEX IF EXISTS reports_open_user_idx;
+CREATE UNIQUE INDEX reports_open_user_idx ON reports (reporter_id, reported_user_id) WHERE status = 'open' AND kind = 'user';
+
+-- +goose Down
+DROP INDEX IF EXISTS reports_open_user_idx;
+CREATE UNIQUE INDEX reports_open_user_idx ON reports (reporter_id, reported_user_id) WHERE status = 'open' AND chirp_id IS NULL;
+ALTER TABLE reports DROP COLUMN IF EXISTS kind;
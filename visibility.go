@@ -0,0 +1,27 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/anton-jj/chripy/internal/database"
+)
+
+// canViewChirp is the single chirp counterpart of the filters in the chirp
+// list queries: chirps hidden by a moderator or written by a limited author
+// are visible to their author only, and blocks hide chirps in both
+// directions. Limited authors are never told; their chirps just stop
+// reaching anyone else.
+func (aCfg *apiConfig) canViewChirp(r *http.Request, chirp database.Chirp) bool {
+	viewer, signedIn := userIDFromContext(r.Context())
+	if signedIn && viewer == chirp.UserID {
+		return true
+	}
+	if chirp.HiddenAt.Valid {
+		return false
+	}
+	author, err := aCfg.db.GetUserById(r.Context(), chirp.UserID)
+	if err != nil || author.LimitedAt.Valid {
+		return false
+	}
+	return !signedIn || !aCfg.isBlocked(r, viewer, chirp.UserID)
+}